@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/feature"
+)
+
+// RequireToken returns an HTTP middleware that only lets requests with the admin token through,
+// sent as "Authorization: Bearer <token>".
+// All requests are rejected when ADMIN_TOKEN is not set, so admin endpoints are disabled by default.
+// They then respond 404 with the same JSON body as unknown routes, without listing any endpoints.
+func RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if env.AdminToken == "" {
+			writeNotFound(w, []string{})
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(env.AdminToken)) != 1 {
+			slog.Warn("Unauthorized admin request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeError writes a JSON error response with the given status code,
+// in the same shape as the feature endpoints' error responses.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(feature.ErrorResponse{Error: message, Code: code})
+}
+
+// writeMethodNotAllowed responds 405 with the allowed method in the Allow header.
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+}
@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/feature"
+)
+
+func TestRequireToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "admin disabled", adminToken: "", authorization: "Bearer secret", wantStatus: http.StatusNotFound},
+		{name: "missing token", adminToken: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: "secret", authorization: "Bearer wrong", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", adminToken: "secret", authorization: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "token prefix", adminToken: "secret", authorization: "Bearer secre", wantStatus: http.StatusUnauthorized},
+		{name: "correct token", adminToken: "secret", authorization: "Bearer secret", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.AdminToken, tt.adminToken)
+
+			reached := false
+			handler := RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("reached the handler = %v with status %d", reached, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK && w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want a JSON error", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRequireTokenRespondsNotFoundAsJSON(t *testing.T) {
+	clientstest.SetForTest(t, &env.AdminToken, "")
+
+	w := httptest.NewRecorder()
+	RequireToken(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var response NotFoundResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Error != "not found" || response.KnownEndpoints == nil || len(response.KnownEndpoints) != 0 {
+		t.Errorf("response %+v, want not found without endpoints", response)
+	}
+}
+
+func TestHandlersRespondMethodNotAllowedAsJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.Handler
+		method    string
+		wantAllow string
+	}{
+		{name: "status", handler: http.HandlerFunc(StatusHandler), method: http.MethodPost, wantAllow: http.MethodGet},
+		{name: "flush-metrics", handler: http.HandlerFunc(FlushMetricsHandler), method: http.MethodGet, wantAllow: http.MethodPost},
+		{name: "reload", handler: http.HandlerFunc(ReloadHandler), method: http.MethodGet, wantAllow: http.MethodPost},
+		{name: "routes", handler: NewRoutes(http.NewServeMux()).Handler(), method: http.MethodPost, wantAllow: http.MethodGet},
+		{name: "sdk-info", handler: http.HandlerFunc(SDKInfoHandler), method: http.MethodPost, wantAllow: http.MethodGet},
+		{name: "selftest", handler: http.HandlerFunc(SelfTestHandler), method: http.MethodPost, wantAllow: http.MethodGet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, httptest.NewRequest(tt.method, "/admin/"+tt.name, nil))
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var response feature.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if response.Code != "method_not_allowed" {
+				t.Errorf("code %q, want method_not_allowed", response.Code)
+			}
+		})
+	}
+}
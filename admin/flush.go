@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// FlushMetricsResponse represents the JSON response for metrics flush requests.
+type FlushMetricsResponse struct {
+	Flushed bool   `json:"flushed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// FlushMetricsHandler handles POST /admin/flush-metrics.
+// The Unleash Go SDK sends usage metrics on its own interval and exposes no way to flush them on demand,
+// so this responds with 501 Not Implemented and logs the attempt.
+func FlushMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	reason := "the Unleash SDK does not support flushing metrics on demand, they are sent on the metrics interval"
+	slog.Info("Flushing Unleash SDK metrics is not supported",
+		slog.String("reason", reason),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotImplemented)
+	json.NewEncoder(w).Encode(FlushMetricsResponse{Flushed: false, Reason: reason})
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestFlushMetricsHandlerIsNotSupported(t *testing.T) {
+	w := httptest.NewRecorder()
+	FlushMetricsHandler(w, httptest.NewRequest(http.MethodPost, "/admin/flush-metrics", nil))
+
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var response FlushMetricsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Flushed || !strings.Contains(response.Reason, "does not support flushing") {
+		t.Errorf("response %+v, want not flushed with the reason it is not supported", response)
+	}
+}
+
+func TestFlushMetricsHandlerRejectsOtherMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	FlushMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/admin/flush-metrics", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if got := w.Header().Get("Allow"); got != http.MethodPost {
+		t.Errorf("Allow = %q, want POST", got)
+	}
+}
+
+func TestFlushMetricsHandlerRequiresToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "admin disabled", adminToken: "", authorization: "Bearer secret", wantStatus: http.StatusNotFound},
+		{name: "missing token", adminToken: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: "secret", authorization: "Bearer wrong", wantStatus: http.StatusUnauthorized},
+		{name: "correct token", adminToken: "secret", authorization: "Bearer secret", wantStatus: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.AdminToken, tt.adminToken)
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/flush-metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			RequireToken(http.HandlerFunc(FlushMetricsHandler)).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
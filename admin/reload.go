@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/feature"
+)
+
+// ReloadResponse represents the JSON response for client reload requests.
+type ReloadResponse struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ReloadHandler handles POST /admin/reload, re-reading the inbound applications and
+// adding or removing Unleash clients to match. Existing clients keep serving requests during the reload.
+// Responds with 409 Conflict while the clients are still being initialized at startup.
+func ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	added, removed, err := clients.Reload()
+	feature.ForgetApps(removed)
+
+	response := ReloadResponse{
+		Added:   added,
+		Removed: removed,
+	}
+	status := http.StatusOK
+
+	if errors.Is(err, clients.ErrNotInitialized) {
+		response.Error = err.Error()
+		status = http.StatusConflict
+	} else if err != nil {
+		slog.Error("Failed to reload Unleash clients",
+			slog.String("error", err.Error()),
+		)
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Route describes an endpoint registered on the server and the methods it accepts.
+type Route struct {
+	Pattern string   `json:"pattern"`
+	Methods []string `json:"methods"`
+}
+
+// Routes records routes as they are registered on a mux, so the list of served endpoints
+// is generated from the actual registrations rather than maintained by hand.
+type Routes struct {
+	mux    *http.ServeMux
+	routes []Route
+}
+
+// NewRoutes creates a Routes registering on the given mux.
+func NewRoutes(mux *http.ServeMux) *Routes {
+	return &Routes{mux: mux}
+}
+
+// Handle registers the handler for the pattern on the mux and records the route.
+func (r *Routes) Handle(pattern string, methods []string, handler http.Handler) {
+	r.mux.Handle(pattern, handler)
+	r.routes = append(r.routes, Route{Pattern: pattern, Methods: methods})
+}
+
+// List returns the registered routes in registration order.
+func (r *Routes) List() []Route {
+	return r.routes
+}
+
+// Handler returns a handler serving the registered routes as JSON, for GET /admin/routes.
+func (r *Routes) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(r.routes)
+	})
+}
+
+// NotFoundResponse represents the JSON response for unknown routes.
+type NotFoundResponse struct {
+	Error          string   `json:"error"`
+	KnownEndpoints []string `json:"known_endpoints"`
+}
+
+// NotFoundHandler returns a handler responding 404 with the registered route patterns as JSON,
+// for use as the catch-all route.
+func (r *Routes) NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		endpoints := make([]string, len(r.routes))
+		for i, route := range r.routes {
+			endpoints[i] = route.Pattern
+		}
+
+		writeNotFound(w, endpoints)
+	})
+}
+
+// writeNotFound responds 404 with a NotFoundResponse listing the given endpoints.
+func writeNotFound(w http.ResponseWriter, endpoints []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(NotFoundResponse{Error: "not found", KnownEndpoints: endpoints})
+}
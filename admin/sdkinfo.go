@@ -0,0 +1,21 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+// SDKInfoHandler handles GET /admin/sdk-info, returning the Unleash SDK version, instance ID,
+// registered strategies and intervals of each app's client.
+func SDKInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(clients.SDKInfos())
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+func TestSDKInfoHandler(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1")
+	clientstest.Eventually(t, func() bool { return clients.SDKInfos()["app-1"].Registered })
+
+	w := httptest.NewRecorder()
+	SDKInfoHandler(w, httptest.NewRequest(http.MethodGet, "/admin/sdk-info", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var infos map[string]clients.SDKInfo
+	if err := json.NewDecoder(w.Body).Decode(&infos); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	info, ok := infos["app-1"]
+	if !ok {
+		t.Fatalf("infos %v, want app-1", infos)
+	}
+	if info.SDKVersion == "" || info.InstanceID == "" || info.RefreshInterval == "" {
+		t.Errorf("app-1 info %+v, want the SDK version, instance ID and refresh interval", info)
+	}
+}
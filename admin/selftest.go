@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+const (
+	// selfTestFeature is evaluated for each app. Whether it exists in Unleash does not matter,
+	// since the self-test checks that evaluation completes, not its result.
+	selfTestFeature = "klage-unleash-proxy-selftest"
+
+	// selfTestTimeout is how long an app's evaluation may take before it fails the self-test.
+	selfTestTimeout = 2 * time.Second
+)
+
+// SelfTestResult is the outcome of the self-test for one app.
+type SelfTestResult struct {
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// SelfTestResponse represents the JSON response for self-test requests.
+type SelfTestResponse struct {
+	Passed bool                      `json:"passed"`
+	Apps   map[string]SelfTestResult `json:"apps"`
+}
+
+// SelfTestHandler handles GET /admin/selftest, evaluating a test feature with each app's Unleash client.
+// Unlike /isReady, it exercises the evaluation path. It responds 503 if any app fails, for post-deploy smoke tests.
+func SelfTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	readyApps, notReadyApps := clients.ReadyApps()
+
+	response := SelfTestResponse{
+		Passed: len(notReadyApps) == 0,
+		Apps:   make(map[string]SelfTestResult, len(readyApps)+len(notReadyApps)),
+	}
+	for _, app := range notReadyApps {
+		response.Apps[app] = SelfTestResult{Error: "client not ready"}
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, app := range readyApps {
+		wg.Add(1)
+		go func(app string) {
+			defer wg.Done()
+
+			result := selfTest(app)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Apps[app] = result
+			if !result.Passed {
+				response.Passed = false
+			}
+		}(app)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if !response.Passed {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// selfTest evaluates the self-test feature with the app's client, failing if it does not complete within selfTestTimeout.
+// The evaluation goes straight to the client, so it is not counted in feature metrics, stats or hooks.
+func selfTest(app string) SelfTestResult {
+	start := time.Now()
+
+	client, ok := clients.Get(app)
+	if !ok {
+		return SelfTestResult{Error: "client not found"}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Evaluate(selfTestFeature, unleashcontext.Context{AppName: app, Environment: clients.Environment(app)})
+	}()
+
+	select {
+	case <-done:
+		return SelfTestResult{Passed: true, Duration: time.Since(start).Milliseconds()}
+	case <-time.After(selfTestTimeout):
+		return SelfTestResult{Error: "evaluation timed out after " + selfTestTimeout.String(), Duration: time.Since(start).Milliseconds()}
+	}
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+func selfTestResponse(t *testing.T) (int, SelfTestResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	SelfTestHandler(w, httptest.NewRequest(http.MethodGet, "/admin/selftest", nil))
+
+	var response SelfTestResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return w.Code, response
+}
+
+func TestSelfTestHandlerPasses(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	status, response := selfTestResponse(t)
+
+	if status != http.StatusOK || !response.Passed {
+		t.Fatalf("status %d, response %+v, want passed", status, response)
+	}
+	for _, app := range []string{"app-1", "app-2"} {
+		if result, ok := response.Apps[app]; !ok || !result.Passed {
+			t.Errorf("%s result %+v, want passed", app, result)
+		}
+	}
+}
+
+func TestSelfTestHandlerFailsOnTimeout(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	evaluator := clientstest.NewBlockingEvaluator(nil)
+	clients.RegisterEvaluator("app-2", evaluator)
+	t.Cleanup(func() {
+		close(evaluator.Release)
+		clients.UnregisterEvaluator("app-2")
+	})
+
+	status, response := selfTestResponse(t)
+
+	if status != http.StatusServiceUnavailable || response.Passed {
+		t.Fatalf("status %d, response %+v, want failed", status, response)
+	}
+	if !response.Apps["app-1"].Passed {
+		t.Errorf("app-1 result %+v, want passed", response.Apps["app-1"])
+	}
+	if result := response.Apps["app-2"]; result.Passed || result.Error == "" {
+		t.Errorf("app-2 result %+v, want a timeout error", result)
+	}
+}
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+// StatusHandler handles GET /status, summarizing the state of each app's Unleash client.
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(clients.Status())
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+func TestStatusHandler(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetToggle("new-ui", true)
+	clientstest.StartClients(t, server, "app-1")
+
+	w := httptest.NewRecorder()
+	StatusHandler(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var statuses map[string]clients.ClientStatus
+	if err := json.NewDecoder(w.Body).Decode(&statuses); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	status, ok := statuses["app-1"]
+	if !ok {
+		t.Fatalf("statuses %v, want app-1", statuses)
+	}
+	if !status.Exists || !status.Ready || status.LastSuccess.IsZero() {
+		t.Errorf("app-1 status %+v, want an existing, ready client with a last success", status)
+	}
+}
+
+func TestStatusHandlerRejectsOtherMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	StatusHandler(w, httptest.NewRequest(http.MethodPost, "/status", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if got := w.Header().Get("Allow"); got != http.MethodGet {
+		t.Errorf("Allow = %q, want GET", got)
+	}
+}
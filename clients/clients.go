@@ -1,77 +1,300 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"os"
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Unleash/unleash-go-sdk/v5"
 	"github.com/navikt/klage-unleash-proxy/env"
 	"github.com/navikt/klage-unleash-proxy/logging"
+	"github.com/navikt/klage-unleash-proxy/metrics"
 	"github.com/navikt/klage-unleash-proxy/nais"
 )
 
 var (
-	// url is the Unleash server API url used by all clients.
-	url       = env.UnleashServerAPIURL + "/api"
-	clientMap = make(map[string]*unleash.Client)
-	mu        sync.RWMutex
-	ready     atomic.Bool
+	// url is the Unleash server API url used by all clients, resolved from UNLEASH_SERVER_API_URL by Initialize.
+	url       string
+	clientMap = make(map[string]*managedClient)
+	// evaluators replace the Unleash clients of apps, see RegisterEvaluator.
+	evaluators = make(map[string]Evaluator)
+	// inboundApps is the current list of allowed inbound applications, replaced by Reload.
+	inboundApps []string
+	mu          sync.RWMutex
+	ready       atomic.Bool
 )
 
-// Ready returns true if all Unleash clients have been initialized.
+// customHeaders are the extra headers sent to Unleash, from UNLEASH_CUSTOM_HEADERS and UNLEASH_APP_CUSTOM_HEADERS.
+// It is set by Initialize and not modified afterwards.
+var customHeaders headerConfig
+
+// refreshInterval is how often clients fetch feature toggles from Unleash.
+// It is the SDK default, set explicitly so it can be reported by SDKInfos. Tests shorten it to observe refreshes.
+var refreshInterval = 15 * time.Second
+
+// instanceID identifies this proxy instance to the Unleash server.
+// It is set explicitly, rather than generated by the SDK, so it can be included in client logs.
+var instanceID = resolveInstanceID()
+
+func resolveInstanceID() string {
+	if env.NaisPodName != "" {
+		return env.NaisPodName
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return env.DefaultServiceName
+}
+
+// Ready returns true once Initialize has finished. Apps whose client was not ready in time
+// may still be missing, see ReadyApps for the readiness of each app.
 func Ready() bool {
 	return ready.Load()
 }
 
-// Initialize creates and initializes Unleash clients for all inbound applications.
-// This should be called once at startup.
+// ReadyApps returns the apps whose Unleash client is ready, and the apps still waiting for theirs, both sorted.
+func ReadyApps() (readyApps []string, notReadyApps []string) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	readyApps, notReadyApps = []string{}, []string{}
+	for _, app := range appsFor(inboundApps) {
+		if _, ok := clientMap[app]; ok {
+			readyApps = append(readyApps, app)
+		} else {
+			notReadyApps = append(notReadyApps, app)
+		}
+	}
+
+	slices.Sort(readyApps)
+	slices.Sort(notReadyApps)
+
+	return readyApps, notReadyApps
+}
+
+// StaleApps returns the apps whose ready Unleash client has not had successful contact with Unleash within threshold, sorted.
+// Every successful response counts as contact, including unchanged toggle fetches every refreshInterval.
+func StaleApps(threshold time.Duration) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	staleApps := []string{}
+	now := time.Now()
+	for app, client := range clientMap {
+		if now.Sub(client.listener.LastSuccess()) > threshold {
+			staleApps = append(staleApps, app)
+		}
+	}
+
+	slices.Sort(staleApps)
+
+	return staleApps
+}
+
+// initReadyTimeout bounds how long Initialize waits for each client to be ready.
+var initReadyTimeout = 30 * time.Second
+
+// Configure reads the client settings from the environment, falling back to the defaults for invalid values.
+// Call this at startup after logging.Initialize, so the warnings about invalid values are logged as JSON.
+func Configure() {
+	initReadyTimeout = env.Duration("INIT_READY_TIMEOUT", env.InitReadyTimeout, initReadyTimeout)
+	initConcurrency = env.Int("INIT_CONCURRENCY", env.InitConcurrency, initConcurrency)
+	reloadReadyTimeout = env.Duration("RELOAD_READY_TIMEOUT", env.ReloadReadyTimeout, reloadReadyTimeout)
+	enforcement = parseEnforcement(env.AppEnforcement)
+}
+
+// Initialize creates and initializes Unleash clients for all inbound applications,
+// and for the default app in permissive mode.
+// It returns an error for invalid configuration. Clients that are not ready within INIT_READY_TIMEOUT are
+// logged and left out, see ReadyApps. This should be called once at startup.
 func Initialize() error {
-	slog.Info(fmt.Sprintf("Initializing Unleash clients for %d applications", len(nais.InboundApps)),
+	url = apiURL(env.UnleashServerAPIURL)
+
+	// Skippable for environments that resolve the host in ways the check can't see, e.g. /etc/hosts tricks
+	if !env.SkipUnleashDNSCheck {
+		if err := checkDNS(context.Background(), url); err != nil {
+			return err
+		}
+	}
+
+	apps := appsFor(nais.InboundApps)
+
+	var err error
+	appTokens, err = parseAppTokens()
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Initializing Unleash clients for %d applications", len(apps)),
 		slog.String("url", url),
 		slog.String("environment", env.UnleashServerAPIEnv),
 		slog.Bool("has_api_key", env.UnleashServerAPIToken != ""),
-		slog.Int("count", len(nais.InboundApps)),
-		slog.Any("apps", nais.InboundApps),
+		slog.Int("app_api_keys", len(appTokens)),
+		slog.Int("count", len(apps)),
+		slog.Any("apps", apps),
+		slog.String("enforcement", enforcement),
 	)
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(nais.InboundApps))
+	customHeaders, err = parseHeaderConfig()
+	if err != nil {
+		return err
+	}
+
+	parsedEnvironments, err := parseEnvironments()
+	if err != nil {
+		return err
+	}
+	environments.Store(&parsedEnvironments)
+
+	if err := validateTokens(apps); err != nil {
+		return err
+	}
+
+	if err := checkShadowToken(); err != nil {
+		return err
+	}
+
+	// Reloads wait for startup, so they cannot create a second client for an app that is still starting
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	mu.Lock()
+	inboundApps = nais.InboundApps
+	mu.Unlock()
+
+	metrics.SetInboundApps(len(nais.InboundApps))
+
+	// An unreachable app must not hold up the others, so its client is given up on after INIT_READY_TIMEOUT.
+	// The ready apps are served, /isReady lists the others as not ready, and a reload retries them.
+	if _, err := createClients(apps, initReadyTimeout); err != nil {
+		slog.Error("Some Unleash clients are not ready, serving the others until a reload",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	ready.Store(true)
+	return nil
+}
 
-	for _, appName := range nais.InboundApps {
+// Get returns the Evaluator for the given app name: the one registered with RegisterEvaluator, if any,
+// and otherwise the app's Unleash client.
+// Returns nil and false if the app is not found.
+func Get(appName string) (Evaluator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if evaluator, ok := evaluators[appName]; ok {
+		return evaluator, true
+	}
+	client, ok := clientMap[appName]
+	if !ok {
+		return nil, false
+	}
+	return sdkEvaluator{c: client}, true
+}
+
+// RegisterEvaluator makes Get return the given Evaluator for the app instead of its Unleash client,
+// e.g. a clientstest.Evaluator in handler tests.
+func RegisterEvaluator(appName string, evaluator Evaluator) {
+	mu.Lock()
+	defer mu.Unlock()
+	evaluators[appName] = evaluator
+}
+
+// UnregisterEvaluator removes the Evaluator registered for the app with RegisterEvaluator.
+func UnregisterEvaluator(appName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(evaluators, appName)
+}
+
+// Close closes all Unleash clients, each once its in-flight evaluations are done.
+// Evaluators obtained before Close evaluate every feature as unknown and disabled afterwards.
+// This should be called during graceful shutdown.
+func Close() {
+	mu.Lock()
+	closing := clientMap
+	clientMap = make(map[string]*managedClient)
+	closingShadow := shadowClients
+	shadowClients = make(map[string]*managedClient)
+	mu.Unlock()
+
+	for appName, client := range closing {
+		slog.Info("Closing Unleash client",
+			slog.String("app_name", appName),
+		)
+		client.close()
+	}
+
+	for _, client := range closingShadow {
+		client.close()
+	}
+}
+
+// InboundApps returns the current list of allowed inbound applications.
+func InboundApps() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Clone(inboundApps)
+}
+
+// IsValidApp checks if the given app name is in the list of allowed inbound apps.
+func IsValidApp(appName string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Contains(inboundApps, appName)
+}
+
+// initConcurrency is the maximum number of clients created at once, to smooth the startup load on Unleash.
+// Zero or less means no limit.
+var initConcurrency = 10
+
+// createClients creates Unleash clients for the given apps concurrently, at most initConcurrency at a time,
+// and waits for them to be ready, each for readyTimeout at most if it is positive.
+// Each client is added to clientMap as soon as it is ready.
+// It returns the clients that were created, along with an error describing any that failed.
+func createClients(apps []string, readyTimeout time.Duration) (map[string]*managedClient, error) {
+	var (
+		wg        sync.WaitGroup
+		createdMu sync.Mutex
+		created   = make(map[string]*managedClient, len(apps))
+	)
+	errChan := make(chan error, len(apps))
+
+	limit := initConcurrency
+	if limit <= 0 {
+		limit = len(apps)
+	}
+	slots := make(chan struct{}, limit)
+
+	for _, appName := range apps {
 		wg.Add(1)
 		go func(app string) {
 			defer wg.Done()
 
-			slog.Info("Initializing Unleash client for "+app,
-				slog.String("app_name", app),
-				slog.String("url", url),
-				slog.String("environment", env.UnleashServerAPIEnv),
-			)
-
-			client, err := unleash.NewClient(
-				unleash.WithListener(logging.NewSlogListener(app)),
-				unleash.WithAppName(app),
-				unleash.WithUrl(url),
-				unleash.WithCustomHeaders(http.Header{"Authorization": {env.UnleashServerAPIToken}}),
-			)
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			client, err := newClient(app, readyTimeout)
 			if err != nil {
-				errChan <- fmt.Errorf("failed to create Unleash client for %s: %w", app, err)
+				errChan <- err
 				return
 			}
 
-			client.WaitForReady()
+			createdMu.Lock()
+			created[app] = client
+			createdMu.Unlock()
 
+			// Serve the app as soon as its client is ready, without waiting for the others
 			mu.Lock()
 			clientMap[app] = client
 			mu.Unlock()
 
-			slog.Info("Unleash client ready for "+app,
-				slog.String("app_name", app),
-			)
+			startShadowClient(app)
 		}(appName)
 	}
 
@@ -85,39 +308,61 @@ func Initialize() error {
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("failed to initialize some Unleash clients: %v", errs)
+		return created, fmt.Errorf("failed to initialize some Unleash clients: %v", errs)
 	}
 
-	ready.Store(true)
-	return nil
+	return created, nil
 }
 
-// Get returns the Unleash client for the given app name.
-// Returns nil and false if the app is not found.
-func Get(appName string) (*unleash.Client, bool) {
-	mu.RLock()
-	defer mu.RUnlock()
-	client, ok := clientMap[appName]
-	return client, ok
-}
+// newClient creates the Unleash client for an app and waits for it to be ready.
+// If readyTimeout is positive and the client is not ready in time, it is closed and an error is returned.
+// Otherwise it waits indefinitely.
+func newClient(app string, readyTimeout time.Duration) (*managedClient, error) {
+	appToken := token(app)
+	headers := customHeaders.headers(app, appToken)
 
-// Close closes all Unleash clients.
-// This should be called during graceful shutdown.
-func Close() {
-	mu.Lock()
-	defer mu.Unlock()
+	slog.Info("Initializing Unleash client for "+app,
+		slog.String("app_name", app),
+		slog.String("url", url),
+		slog.String("environment", Environment(app)),
+		slog.String("token", RedactToken(appToken)),
+		slog.Any("headers", headerNames(headers)),
+	)
 
-	for appName, client := range clientMap {
-		slog.Info("Closing Unleash client",
-			slog.String("app_name", appName),
-		)
-		client.Close()
+	storage := &featureStorage{}
+	listener := logging.NewSlogListener(app, instanceID, Environment(app), url)
+
+	client, err := unleash.NewClient(
+		unleash.WithListener(listener),
+		unleash.WithAppName(app),
+		unleash.WithInstanceId(instanceID),
+		unleash.WithUrl(url),
+		unleash.WithCustomHeaders(headers),
+		unleash.WithRefreshInterval(refreshInterval),
+		unleash.WithStorage(storage),
+		unleash.WithHttpClient(newHTTPClient(listener)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Unleash client for %s: %w", app, err)
 	}
 
-	clientMap = make(map[string]*unleash.Client)
-}
+	metrics.RegisterClientHealth(app, listener.Health)
 
-// IsValidApp checks if the given app name is in the list of allowed inbound apps.
-func IsValidApp(appName string) bool {
-	return slices.Contains(nais.InboundApps, appName)
+	if readyTimeout > 0 {
+		select {
+		case <-listener.ReadyChan():
+		case <-time.After(readyTimeout):
+			client.Close()
+			metrics.UnregisterClientHealth(app)
+			return nil, fmt.Errorf("Unleash client for %s not ready within %s", app, readyTimeout)
+		}
+	} else {
+		client.WaitForReady()
+	}
+
+	slog.Info("Unleash client ready for "+app,
+		slog.String("app_name", app),
+	)
+
+	return &managedClient{client: client, listener: listener, storage: storage}, nil
 }
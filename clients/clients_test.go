@@ -0,0 +1,32 @@
+package clients_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+func TestCreateClientsLimitsConcurrency(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetFetchDelay(100 * time.Millisecond)
+
+	const limit = 2
+	t.Cleanup(clients.SetInitConcurrency(limit))
+	clientstest.UseServer(t, server)
+
+	closeAll, err := clients.CreateClients(server.URL, []string{"app-1", "app-2", "app-3", "app-4", "app-5"})
+	defer closeAll()
+	if err != nil {
+		t.Fatalf("CreateClients: %v", err)
+	}
+
+	if got := server.MaxConcurrentFetches(); got > limit {
+		t.Errorf("max concurrent fetches = %d, want at most %d", got, limit)
+	}
+	if got := server.Fetches(); got < 5 {
+		t.Errorf("fetches = %d, want one per app", got)
+	}
+}
@@ -0,0 +1,94 @@
+// Package clientstest provides fakes for testing without a real Unleash server: an Evaluator for feature handlers,
+// a Server for Unleash clients, and helpers to start clients against it.
+package clientstest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Unleash/unleash-go-sdk/v5/api"
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+// Evaluator is a fake clients.Evaluator with fixed results, which records the contexts it evaluates.
+// Register it for an app with clients.RegisterEvaluator.
+type Evaluator struct {
+	// Features maps the known features to whether they are enabled. Other features are unknown and disabled.
+	Features map[string]bool
+	// Variants maps features to their resolved variant. Other features resolve to the disabled variant,
+	// and are known if they are in Features.
+	Variants map[string]*api.Variant
+
+	mu          sync.Mutex
+	contexts    []unleashcontext.Context
+	lastSuccess time.Time
+}
+
+var (
+	_ clients.Evaluator = (*Evaluator)(nil)
+	_ clients.Freshness = (*Evaluator)(nil)
+)
+
+// NewEvaluator creates an Evaluator with the given known features.
+func NewEvaluator(features map[string]bool) *Evaluator {
+	return &Evaluator{Features: features}
+}
+
+func (e *Evaluator) IsEnabled(name string, ctx unleashcontext.Context) bool {
+	enabled, _ := e.Evaluate(name, ctx)
+	return enabled
+}
+
+func (e *Evaluator) Evaluate(name string, ctx unleashcontext.Context) (enabled bool, known bool) {
+	e.record(ctx)
+	enabled, known = e.Features[name]
+	return enabled, known
+}
+
+func (e *Evaluator) GetVariant(name string, ctx unleashcontext.Context) (variant *api.Variant, known bool) {
+	e.record(ctx)
+	if variant, ok := e.Variants[name]; ok {
+		return variant, true
+	}
+	_, known = e.Features[name]
+	return api.GetDefaultVariant(), known
+}
+
+// Contexts returns the contexts evaluated so far, in order.
+func (e *Evaluator) Contexts() []unleashcontext.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]unleashcontext.Context(nil), e.contexts...)
+}
+
+// Evaluations returns the number of evaluations so far.
+func (e *Evaluator) Evaluations() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.contexts)
+}
+
+func (e *Evaluator) record(ctx unleashcontext.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.contexts = append(e.contexts, ctx)
+}
+
+// LastSuccess returns the time set with SetLastSuccess, or the current time if none is set,
+// so the Evaluator is fresh unless a test makes it stale.
+func (e *Evaluator) LastSuccess() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastSuccess.IsZero() {
+		return time.Now()
+	}
+	return e.lastSuccess
+}
+
+// SetLastSuccess sets the time of the last successful contact with Unleash, e.g. in the past to make the Evaluator stale.
+func (e *Evaluator) SetLastSuccess(t time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastSuccess = t
+}
@@ -0,0 +1,160 @@
+package clientstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Unleash/unleash-go-sdk/v5/api"
+)
+
+// Server is a fake Unleash server, for testing Unleash clients end to end.
+// Its API is served under /api, so clients are pointed at Server.URL as UNLEASH_SERVER_API_URL.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	features      map[string]api.Feature
+	version       int
+	fetchDelay    time.Duration
+	fetching      int
+	maxFetching   int
+	fetches       int
+	notModified   int
+	registrations int
+	fetchHeaders  map[string]http.Header
+}
+
+// NewServer starts a Server without any features. It is closed with Close.
+func NewServer() *Server {
+	s := &Server{features: make(map[string]api.Feature), fetchHeaders: make(map[string]http.Header)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/client/features", s.handleFeatures)
+	mux.HandleFunc("POST /api/client/register", s.handleRegister)
+	mux.HandleFunc("POST /api/client/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetToggle adds or replaces a feature that is enabled or disabled for everyone.
+func (s *Server) SetToggle(name string, enabled bool) {
+	s.SetFeature(api.Feature{
+		Name:       name,
+		Enabled:    enabled,
+		Strategies: []api.Strategy{{Name: "default"}},
+	})
+}
+
+// SetFeature adds or replaces a feature.
+func (s *Server) SetFeature(feature api.Feature) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features[feature.Name] = feature
+	s.version++
+}
+
+// RemoveToggle removes a feature, so it is unknown to clients after their next fetch.
+func (s *Server) RemoveToggle(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.features, name)
+	s.version++
+}
+
+// SetFetchDelay delays every feature fetch response, e.g. to observe how many fetches run at once.
+func (s *Server) SetFetchDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetchDelay = delay
+}
+
+// Fetches returns the number of feature fetches served, including those answered with 304 Not Modified.
+func (s *Server) Fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// NotModified returns the number of feature fetches answered with 304 Not Modified.
+func (s *Server) NotModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// MaxConcurrentFetches returns the highest number of feature fetches that were in flight at once.
+func (s *Server) MaxConcurrentFetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxFetching
+}
+
+// Registrations returns the number of client registrations received.
+func (s *Server) Registrations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.registrations
+}
+
+// FetchHeaders returns the headers of the latest feature fetch by the client of the given app, or nil if it has not fetched.
+func (s *Server) FetchHeaders(app string) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetchHeaders[app].Clone()
+}
+
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.fetches++
+	s.fetchHeaders[r.Header.Get("Unleash-Appname")] = r.Header.Clone()
+	s.fetching++
+	s.maxFetching = max(s.maxFetching, s.fetching)
+	delay := s.fetchDelay
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.fetching--
+		s.mu.Unlock()
+	}()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	s.mu.Lock()
+	etag := fmt.Sprintf(`"%d"`, s.version)
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	response := api.FeatureResponse{Features: make([]api.Feature, 0, len(s.features))}
+	for _, feature := range s.features {
+		response.Features = append(response.Features, feature)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.registrations++
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
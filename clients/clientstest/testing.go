@@ -0,0 +1,72 @@
+package clientstest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/nais"
+)
+
+// SetForTest sets a package variable for the duration of the test.
+func SetForTest[T any](t testing.TB, variable *T, value T) {
+	t.Helper()
+	previous := *variable
+	*variable = value
+	t.Cleanup(func() { *variable = previous })
+}
+
+// UseServer configures the clients to use the server for the duration of the test.
+func UseServer(t testing.TB, server *Server) {
+	t.Helper()
+	SetForTest(t, &env.UnleashServerAPIURL, server.URL)
+	SetForTest(t, &env.UnleashServerAPIToken, "default:development.secret")
+	SetForTest(t, &env.SkipUnleashDNSCheck, true)
+}
+
+// StartClients points the clients at the server, allows the given apps and initializes their clients,
+// restoring the configuration and closing the clients when the test is done.
+func StartClients(t testing.TB, server *Server, apps ...string) {
+	t.Helper()
+	UseServer(t, server)
+	SetForTest(t, &env.InboundApps, strings.Join(apps, ","))
+	SetForTest(t, &nais.InboundApps, apps)
+
+	if err := clients.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(clients.Close)
+}
+
+// Eventually fails the test if cond does not become true within 5 seconds.
+func Eventually(t testing.TB, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// BlockingEvaluator is an Evaluator whose evaluations don't complete until Release is closed.
+type BlockingEvaluator struct {
+	*Evaluator
+	Release chan struct{}
+}
+
+var _ clients.Evaluator = (*BlockingEvaluator)(nil)
+
+// NewBlockingEvaluator creates a BlockingEvaluator with the given known features.
+func NewBlockingEvaluator(features map[string]bool) *BlockingEvaluator {
+	return &BlockingEvaluator{Evaluator: NewEvaluator(features), Release: make(chan struct{})}
+}
+
+func (e *BlockingEvaluator) Evaluate(name string, ctx unleashcontext.Context) (bool, bool) {
+	<-e.Release
+	return e.Evaluator.Evaluate(name, ctx)
+}
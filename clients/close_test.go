@@ -0,0 +1,60 @@
+package clients_test
+
+import (
+	"sync"
+	"testing"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+// TestEvaluateDuringClose evaluates features while the clients are closed, for the race detector:
+// run with go test -race. Evaluators obtained before Close must keep working, as unknown and disabled.
+func TestEvaluateDuringClose(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetToggle("new-ui", true)
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	evaluators := make([]clients.Evaluator, 0, 2)
+	for _, app := range []string{"app-1", "app-2"} {
+		evaluator, ok := clients.Get(app)
+		if !ok {
+			t.Fatalf("no client for %s", app)
+		}
+		evaluators = append(evaluators, evaluator)
+	}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, evaluator := range evaluators {
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				ctx := unleashcontext.Context{UserId: "Z123456"}
+				for range 200 {
+					evaluator.IsEnabled("new-ui", ctx)
+					evaluator.Evaluate("new-ui", ctx)
+					evaluator.GetVariant("new-ui", ctx)
+					clients.Get("app-1")
+				}
+			}()
+		}
+	}
+
+	close(start)
+	clients.Close()
+	wg.Wait()
+
+	for i, evaluator := range evaluators {
+		if enabled, known := evaluator.Evaluate("new-ui", unleashcontext.Context{}); enabled || known {
+			t.Errorf("evaluator %d after Close: enabled %v, known %v, want unknown and disabled", i, enabled, known)
+		}
+	}
+	if _, ok := clients.Get("app-1"); ok {
+		t.Error("Get returned a client after Close")
+	}
+}
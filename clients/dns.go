@@ -0,0 +1,47 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	neturl "net/url"
+	"time"
+)
+
+// dnsTimeout bounds the DNS lookup of the Unleash API host at startup.
+const dnsTimeout = 5 * time.Second
+
+// checkDNS resolves the host of the Unleash API URL, so an unresolvable hostname
+// fails startup within seconds instead of surfacing later through the SDK's retries.
+func checkDNS(ctx context.Context, rawURL string) error {
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid Unleash API URL %q: %w", rawURL, err)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("Unleash API URL %q has no host", rawURL)
+	}
+
+	// IP addresses need no resolution
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve Unleash API host %s: %w", host, err)
+	}
+
+	slog.Info("Resolved Unleash API host "+host,
+		slog.String("host", host),
+		slog.Any("addresses", addrs),
+	)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package clients_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestCheckDNS(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr string
+	}{
+		{url: "https://localhost/api"},
+		{url: "http://127.0.0.1:4242/api"},
+		{url: "http://[::1]:4242/api"},
+		// .invalid is reserved never to resolve (RFC 6761)
+		{url: "https://unleash.invalid/api", wantErr: "failed to resolve Unleash API host unleash.invalid"},
+		{url: "/api", wantErr: "has no host"},
+		{url: "https://unleash example/api", wantErr: "invalid Unleash API URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := clients.CheckDNS(tt.url)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckDNS(%q): %v", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckDNS(%q) = %v, want an error mentioning %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitializeChecksDNS(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.UseServer(t, server)
+	clientstest.SetForTest(t, &env.UnleashServerAPIURL, "https://unleash.invalid")
+
+	clientstest.SetForTest(t, &env.SkipUnleashDNSCheck, false)
+	if err := clients.Initialize(); err == nil || !strings.Contains(err.Error(), "unleash.invalid") {
+		t.Errorf("Initialize() = %v, want an error for the unresolvable host", err)
+	}
+}
@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// Enforcement modes for APP_ENFORCEMENT.
+const (
+	// EnforcementStrict rejects requests from apps that are not inbound applications.
+	EnforcementStrict = "strict"
+	// EnforcementPermissive evaluates requests from unregistered apps with the default client.
+	EnforcementPermissive = "permissive"
+)
+
+// enforcement is the app allow-list enforcement mode, strict unless APP_ENFORCEMENT is permissive.
+// It is set from APP_ENFORCEMENT by Configure.
+var enforcement = EnforcementStrict
+
+func parseEnforcement(value string) string {
+	switch value {
+	case "", EnforcementStrict:
+		return EnforcementStrict
+	case EnforcementPermissive:
+		return EnforcementPermissive
+	default:
+		slog.Warn("Invalid APP_ENFORCEMENT, using "+EnforcementStrict,
+			slog.String("value", value),
+		)
+		return EnforcementStrict
+	}
+}
+
+// Permissive returns true if requests from unregistered apps are evaluated with the default client.
+func Permissive() bool {
+	return enforcement == EnforcementPermissive
+}
+
+// defaultApp is the Unleash app name of the default client, used for unregistered apps in permissive mode.
+var defaultApp = resolveDefaultApp()
+
+func resolveDefaultApp() string {
+	if env.NaisAppName != "" {
+		return env.NaisAppName
+	}
+	return env.DefaultServiceName
+}
+
+// appsFor returns the apps to create Unleash clients for:
+// the given inbound applications, plus the default app in permissive mode.
+func appsFor(inbound []string) []string {
+	if !Permissive() || slices.Contains(inbound, defaultApp) {
+		return inbound
+	}
+	return append(slices.Clone(inbound), defaultApp)
+}
+
+// Default returns the Evaluator of the default client.
+// Returns nil and false unless in permissive mode.
+func Default() (Evaluator, bool) {
+	if !Permissive() {
+		return nil, false
+	}
+	return Get(defaultApp)
+}
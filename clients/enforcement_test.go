@@ -0,0 +1,117 @@
+package clients_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/feature"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const unregisteredApp = "unregistered-app"
+
+// withDefaultEvaluator serves the default app from a fake Evaluator with the given features for the duration of the test.
+func withDefaultEvaluator(t *testing.T, features map[string]bool) *clientstest.Evaluator {
+	t.Helper()
+	evaluator := clientstest.NewEvaluator(features)
+	clients.RegisterEvaluator(clients.DefaultApp(), evaluator)
+	t.Cleanup(func() { clients.UnregisterEvaluator(clients.DefaultApp()) })
+	return evaluator
+}
+
+// checkUnregistered checks a feature for unregisteredApp through feature.Handler.
+func checkUnregistered(name string) *httptest.ResponseRecorder {
+	body := strings.NewReader(`{"appName":"` + unregisteredApp + `","navIdent":"Z123456"}`)
+	w := httptest.NewRecorder()
+	feature.Handler(w, httptest.NewRequest(http.MethodPost, feature.PathPrefix+name, body))
+	return w
+}
+
+func TestStrictEnforcementRejectsUnregisteredApps(t *testing.T) {
+	feature.InitTracer()
+
+	for _, value := range []string{"", "strict", "invalid"} {
+		t.Run("APP_ENFORCEMENT="+value, func(t *testing.T) {
+			t.Cleanup(clients.SetEnforcement(value))
+			evaluator := withDefaultEvaluator(t, map[string]bool{"new-ui": true})
+
+			if clients.Permissive() {
+				t.Fatal("Permissive = true, want strict")
+			}
+			if _, ok := clients.Default(); ok {
+				t.Error("Default returned a client in strict mode")
+			}
+
+			w := checkUnregistered("new-ui")
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d, body %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+			var response feature.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != "unknown_app_name" {
+				t.Errorf("response %+v, err %v, want code unknown_app_name", response, err)
+			}
+			if got := evaluator.Evaluations(); got != 0 {
+				t.Errorf("%d evaluations with the default client, want none", got)
+			}
+		})
+	}
+}
+
+func TestPermissiveEnforcementUsesTheDefaultClient(t *testing.T) {
+	feature.InitTracer()
+	t.Cleanup(clients.SetEnforcement("permissive"))
+	evaluator := withDefaultEvaluator(t, map[string]bool{"new-ui": true})
+
+	unregistered := metrics.UnregisteredAppRequests.WithLabelValues(unregisteredApp)
+	before := testutil.ToFloat64(unregistered)
+
+	w := checkUnregistered("new-ui")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response feature.Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || !response.Enabled {
+		t.Errorf("response %+v, err %v, want enabled by the default client", response, err)
+	}
+	if got := evaluator.Evaluations(); got != 1 {
+		t.Errorf("%d evaluations with the default client, want 1", got)
+	}
+	if got := testutil.ToFloat64(unregistered) - before; got != 1 {
+		t.Errorf("unregistered_app_requests_total{app_name=%q} increased by %v, want 1", unregisteredApp, got)
+	}
+
+	t.Run("still requires a valid feature name", func(t *testing.T) {
+		before := testutil.ToFloat64(unregistered)
+
+		w := checkUnregistered("..")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		if got := testutil.ToFloat64(unregistered) - before; got != 0 {
+			t.Errorf("unregistered_app_requests_total increased by %v for an invalid feature, want 0", got)
+		}
+	})
+}
+
+func TestConfigureReadsEnforcement(t *testing.T) {
+	t.Cleanup(clients.SetEnforcement(clients.EnforcementStrict))
+	previous := env.AppEnforcement
+	env.AppEnforcement = clients.EnforcementPermissive
+	t.Cleanup(func() { env.AppEnforcement = previous })
+
+	clients.Configure()
+
+	if !clients.Permissive() {
+		t.Error("APP_ENFORCEMENT=permissive is not applied by Configure")
+	}
+}
@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// environments maps app names to their Unleash environment, from UNLEASH_APP_ENVIRONMENTS.
+// It is set by Initialize, while requests may already be evaluated, and not modified afterwards.
+var environments atomic.Pointer[map[string]string]
+
+// parseEnvironments parses UNLEASH_APP_ENVIRONMENTS (app to Unleash environment) from JSON.
+func parseEnvironments() (map[string]string, error) {
+	if env.UnleashAppEnvironments == "" {
+		return nil, nil
+	}
+
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(env.UnleashAppEnvironments), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid UNLEASH_APP_ENVIRONMENTS: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// Environment returns the Unleash environment used to evaluate features for the given app.
+// Falls back to UNLEASH_SERVER_API_ENV when the app has no environment of its own.
+func Environment(appName string) string {
+	if parsed := environments.Load(); parsed != nil {
+		if environment := (*parsed)[appName]; environment != "" {
+			return environment
+		}
+	}
+	return env.UnleashServerAPIEnv
+}
+
+// checkTokenEnvironment verifies that a token scoped to a single environment matches the app's environment.
+// Tokens shaped like "<project>:<environment>.<secret>" carry their environment; other tokens can't be checked.
+func checkTokenEnvironment(appName, token string) error {
+	tokenEnv, ok := tokenEnvironment(token)
+	if !ok || tokenEnv == "*" {
+		return nil
+	}
+
+	if environment := Environment(appName); environment != "" && environment != tokenEnv {
+		return fmt.Errorf("Unleash environment %q for %s does not match the API token's environment %q", environment, appName, tokenEnv)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package clients_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/nais"
+)
+
+func TestAppEnvironments(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+
+	clientstest.SetForTest(t, &env.UnleashServerAPIEnv, "development")
+	clientstest.SetForTest(t, &env.UnleashAppEnvironments, `{"kabal-api":"production","kabal-frontend":""}`)
+	clientstest.SetForTest(t, &env.UnleashAppAPITokens, `{"kabal-api":"default:production.secret"}`)
+	clientstest.StartClients(t, server, "kabal-frontend", "kabal-api", "kabal-search")
+
+	for app, want := range map[string]string{
+		"kabal-api":      "production",
+		"kabal-frontend": "development",
+		"kabal-search":   "development",
+	} {
+		if got := clients.Environment(app); got != want {
+			t.Errorf("Environment(%q) = %q, want %q", app, got, want)
+		}
+	}
+}
+
+func TestInitializeRejectsInvalidAppEnvironments(t *testing.T) {
+	tests := []struct {
+		name         string
+		environments string
+		tokens       string
+		wantErr      string
+	}{
+		{
+			name:         "malformed JSON",
+			environments: `["production"]`,
+			wantErr:      "UNLEASH_APP_ENVIRONMENTS",
+		},
+		{
+			name:         "shared token for another environment",
+			environments: `{"kabal-api":"production"}`,
+			wantErr:      "does not match",
+		},
+		{
+			name:         "app token for another environment",
+			environments: `{"kabal-api":"production"}`,
+			tokens:       `{"kabal-api":"default:staging.secret"}`,
+			wantErr:      "does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := clientstest.NewServer()
+			t.Cleanup(server.Close)
+			clientstest.UseServer(t, server)
+			clientstest.SetForTest(t, &env.UnleashServerAPIEnv, "development")
+			clientstest.SetForTest(t, &env.UnleashAppEnvironments, tt.environments)
+			clientstest.SetForTest(t, &env.UnleashAppAPITokens, tt.tokens)
+			clientstest.SetForTest(t, &env.InboundApps, "kabal-api")
+			clientstest.SetForTest(t, &nais.InboundApps, []string{"kabal-api"})
+
+			if err := clients.Initialize(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Initialize() = %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Unleash/unleash-go-sdk/v5"
+	"github.com/Unleash/unleash-go-sdk/v5/api"
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/logging"
+)
+
+// Evaluator evaluates feature toggles for a single application.
+// It decouples the feature handler from the Unleash SDK, so handlers can be exercised with a fake,
+// such as clientstest.Evaluator registered with RegisterEvaluator.
+type Evaluator interface {
+	// IsEnabled returns whether the named feature is enabled for the given context.
+	IsEnabled(name string, ctx unleashcontext.Context) bool
+	// Evaluate returns whether the named feature is enabled for the given context,
+	// and whether the feature is known to Unleash. Unknown features are never enabled.
+	Evaluate(name string, ctx unleashcontext.Context) (enabled bool, known bool)
+	// GetVariant returns the resolved variant of the named feature for the given context,
+	// and whether the feature is known to Unleash. Unknown features resolve to the disabled variant.
+	GetVariant(name string, ctx unleashcontext.Context) (variant *api.Variant, known bool)
+}
+
+// Freshness is implemented by Evaluators that know when they last had successful contact with Unleash,
+// and so how old their feature data may be.
+type Freshness interface {
+	// LastSuccess returns the time of the last successful contact with Unleash.
+	LastSuccess() time.Time
+}
+
+// managedClient guards an Unleash SDK client against use after it is closed.
+// A closed SDK client no longer drains its metrics channel, so evaluating on it would block forever.
+// Evaluations hold the read lock, so close waits for in-flight evaluations, and later ones see closed.
+type managedClient struct {
+	mu     sync.RWMutex
+	client *unleash.Client
+	// listener receives the SDK client's events, and is kept for the data it captures.
+	listener *logging.SlogListener
+	// storage holds the SDK client's features, to look up whether a feature is known.
+	storage *featureStorage
+	closed  bool
+}
+
+// close closes the SDK client once all in-flight evaluations are done.
+func (c *managedClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.client.Close()
+}
+
+// sdkEvaluator is the Evaluator backed by an Unleash SDK client.
+// Once the client is closed, features evaluate as unknown and disabled.
+type sdkEvaluator struct {
+	c *managedClient
+}
+
+func (e sdkEvaluator) IsEnabled(name string, ctx unleashcontext.Context) bool {
+	e.c.mu.RLock()
+	defer e.c.mu.RUnlock()
+
+	if e.c.closed {
+		return false
+	}
+	return e.c.client.IsEnabled(name, unleash.WithContext(ctx))
+}
+
+func (e sdkEvaluator) Evaluate(name string, ctx unleashcontext.Context) (enabled bool, known bool) {
+	e.c.mu.RLock()
+	defer e.c.mu.RUnlock()
+
+	if e.c.closed {
+		return false, false
+	}
+
+	known = true
+	// The SDK only calls the fallback function when the feature is not in its repository
+	enabled = e.c.client.IsEnabled(name, unleash.WithContext(ctx), unleash.WithFallbackFunc(func(string, *unleashcontext.Context) bool {
+		known = false
+		return false
+	}))
+	return enabled, known
+}
+
+func (e sdkEvaluator) GetVariant(name string, ctx unleashcontext.Context) (variant *api.Variant, known bool) {
+	e.c.mu.RLock()
+	defer e.c.mu.RUnlock()
+
+	if e.c.closed {
+		return api.GetDefaultVariant(), false
+	}
+
+	variant = e.c.client.GetVariant(name, unleash.WithVariantContext(ctx))
+	if variant.FeatureEnabled {
+		return variant, true
+	}
+
+	// The SDK resolves unknown and disabled features to the same variant, and calls a variant fallback for both,
+	// so look the feature up
+	return variant, e.c.storage.known(name)
+}
+
+// LastSuccess returns the time of the client's last successful contact with Unleash.
+func (e sdkEvaluator) LastSuccess() time.Time {
+	return e.c.listener.LastSuccess()
+}
@@ -0,0 +1,81 @@
+package clients
+
+import (
+	"context"
+	"time"
+)
+
+// SetRefreshInterval shortens how often clients fetch feature toggles, returning a func that restores it.
+func SetRefreshInterval(interval time.Duration) (restore func()) {
+	previous := refreshInterval
+	refreshInterval = interval
+	return func() { refreshInterval = previous }
+}
+
+// SetInitConcurrency sets INIT_CONCURRENCY, returning a func that restores it.
+func SetInitConcurrency(limit int) (restore func()) {
+	previous := initConcurrency
+	initConcurrency = limit
+	return func() { initConcurrency = previous }
+}
+
+// CreateClients creates clients for the given apps with createClients, returning a func that closes them.
+func CreateClients(serverURL string, apps []string) (closeAll func(), err error) {
+	previousURL := url
+	url = apiURL(serverURL)
+	created, err := createClients(apps, 5*time.Second)
+	return func() {
+		mu.Lock()
+		for app := range created {
+			delete(clientMap, app)
+		}
+		mu.Unlock()
+		for _, client := range created {
+			client.close()
+		}
+		url = previousURL
+	}, err
+}
+
+// SetReloadReadyTimeout sets RELOAD_READY_TIMEOUT, returning a func that restores it.
+func SetReloadReadyTimeout(timeout time.Duration) (restore func()) {
+	previous := reloadReadyTimeout
+	reloadReadyTimeout = timeout
+	return func() { reloadReadyTimeout = previous }
+}
+
+// SetInitReadyTimeout sets INIT_READY_TIMEOUT, returning a func that restores it.
+func SetInitReadyTimeout(timeout time.Duration) (restore func()) {
+	previous := initReadyTimeout
+	initReadyTimeout = timeout
+	return func() { initReadyTimeout = previous }
+}
+
+// SetReady sets whether Initialize has finished, returning a func that restores it.
+func SetReady(value bool) (restore func()) {
+	previous := ready.Load()
+	ready.Store(value)
+	return func() { ready.Store(previous) }
+}
+
+// APIURL resolves the Unleash API url from UNLEASH_SERVER_API_URL with apiURL.
+func APIURL(serverURL string) string {
+	return apiURL(serverURL)
+}
+
+// CheckDNS resolves the host of the Unleash API URL with checkDNS.
+func CheckDNS(rawURL string) error {
+	return checkDNS(context.Background(), rawURL)
+}
+
+// SetEnforcement sets APP_ENFORCEMENT, returning a func that restores it.
+func SetEnforcement(value string) (restore func()) {
+	previous := enforcement
+	enforcement = parseEnforcement(value)
+	return func() { enforcement = previous }
+}
+
+// DefaultApp returns the app name of the default client used in permissive mode.
+func DefaultApp() string {
+	return defaultApp
+}
@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// headerConfig holds the extra headers sent to Unleash, on top of Authorization.
+type headerConfig struct {
+	global map[string]string
+	perApp map[string]map[string]string
+}
+
+// parseHeaderConfig parses UNLEASH_CUSTOM_HEADERS (header to value)
+// and UNLEASH_APP_CUSTOM_HEADERS (app to header to value) from JSON.
+func parseHeaderConfig() (headerConfig, error) {
+	var config headerConfig
+
+	if env.UnleashCustomHeaders != "" {
+		if err := json.Unmarshal([]byte(env.UnleashCustomHeaders), &config.global); err != nil {
+			return config, fmt.Errorf("invalid UNLEASH_CUSTOM_HEADERS: %w", err)
+		}
+	}
+
+	if env.UnleashAppCustomHeaders != "" {
+		if err := json.Unmarshal([]byte(env.UnleashAppCustomHeaders), &config.perApp); err != nil {
+			return config, fmt.Errorf("invalid UNLEASH_APP_CUSTOM_HEADERS: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
+// headers returns the headers for the Unleash client of the given app.
+// Per-app headers take precedence over global ones, and neither can override Authorization.
+func (c headerConfig) headers(app, token string) http.Header {
+	header := http.Header{}
+
+	for _, extra := range []map[string]string{c.global, c.perApp[app]} {
+		for name, value := range extra {
+			if http.CanonicalHeaderKey(name) == "Authorization" {
+				slog.Warn("Ignoring custom Authorization header for "+app+", the API token is always used",
+					slog.String("app_name", app),
+				)
+				continue
+			}
+			header.Set(name, value)
+		}
+	}
+
+	header.Set("Authorization", token)
+
+	return header
+}
+
+// headerNames returns the sorted header names, for logging without exposing values.
+func headerNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
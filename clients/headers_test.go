@@ -0,0 +1,66 @@
+package clients_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestCustomHeaders(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+
+	clientstest.SetForTest(t, &env.UnleashCustomHeaders, `{"X-Team":"klage","X-Env":"dev","Authorization":"Bearer spoofed"}`)
+	clientstest.SetForTest(t, &env.UnleashAppCustomHeaders, `{"kabal-frontend":{"X-Env":"kabal","X-Tenant":"kabal"}}`)
+	clientstest.StartClients(t, server, "kabal-frontend", "kabal-api")
+
+	tests := []struct {
+		app  string
+		want map[string]string
+	}{
+		{
+			app:  "kabal-frontend",
+			want: map[string]string{"X-Team": "klage", "X-Env": "kabal", "X-Tenant": "kabal", "Authorization": "default:development.secret"},
+		},
+		{
+			app:  "kabal-api",
+			want: map[string]string{"X-Team": "klage", "X-Env": "dev", "X-Tenant": "", "Authorization": "default:development.secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			headers := server.FetchHeaders(tt.app)
+			if headers == nil {
+				t.Fatalf("no feature fetch by %s", tt.app)
+			}
+			for name, want := range tt.want {
+				if got := headers.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInitializeRejectsInvalidCustomHeaders(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.UseServer(t, server)
+
+	for name, variable := range map[string]*string{
+		"UNLEASH_CUSTOM_HEADERS":     &env.UnleashCustomHeaders,
+		"UNLEASH_APP_CUSTOM_HEADERS": &env.UnleashAppCustomHeaders,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clientstest.SetForTest(t, variable, `["X-Team"]`)
+
+			if err := clients.Initialize(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("Initialize() = %v, want an error for invalid %s", err, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package clients_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/feature"
+)
+
+const testApp = "kabal-frontend"
+
+func TestInitializeEvaluatesAgainstUnleash(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetToggle("new-ui", true)
+	server.SetToggle("dark-mode", false)
+	server.SetToggle("old-ui", false)
+
+	t.Cleanup(clients.SetRefreshInterval(50 * time.Millisecond))
+	clientstest.StartClients(t, server, testApp)
+	feature.InitTracer()
+
+	if !clients.Ready() {
+		t.Fatal("clients not ready after Initialize")
+	}
+	if server.Registrations() == 0 {
+		t.Error("client did not register with Unleash")
+	}
+
+	assertEvaluation(t, "new-ui", true)
+	assertEvaluation(t, "dark-mode", false)
+
+	t.Run("variants tell disabled from unknown features", func(t *testing.T) {
+		evaluator, ok := clients.Get(testApp)
+		if !ok {
+			t.Fatal("no client for " + testApp)
+		}
+		ctx := unleashcontext.Context{AppName: testApp}
+
+		for name, wantKnown := range map[string]bool{"old-ui": true, "missing": false} {
+			variant, known := evaluator.GetVariant(name, ctx)
+			if variant.FeatureEnabled || known != wantKnown {
+				t.Errorf("%s: FeatureEnabled = %v, known = %v, want false, %v", name, variant.FeatureEnabled, known, wantKnown)
+			}
+		}
+	})
+
+	t.Run("refreshes changed toggles", func(t *testing.T) {
+		server.SetToggle("dark-mode", true)
+		clientstest.Eventually(t, func() bool { return evaluate(t, "dark-mode") })
+	})
+
+	t.Run("304 responses keep clients fresh", func(t *testing.T) {
+		notModified := server.NotModified()
+		changed := time.Now()
+		clientstest.Eventually(t, func() bool {
+			return server.NotModified() >= notModified+5 && time.Since(changed) > 300*time.Millisecond
+		})
+
+		if stale := clients.StaleApps(200 * time.Millisecond); len(stale) > 0 {
+			t.Errorf("StaleApps = %v, want none while Unleash answers 304 Not Modified", stale)
+		}
+	})
+}
+
+// evaluate checks a feature for testApp through feature.Handler.
+func evaluate(t *testing.T, name string) bool {
+	t.Helper()
+
+	body := strings.NewReader(`{"appName":"` + testApp + `","navIdent":"Z123456","podName":"test-pod"}`)
+	r := httptest.NewRequest(http.MethodPost, feature.PathPrefix+name, body)
+	w := httptest.NewRecorder()
+	feature.Handler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d, body %s", name, w.Code, w.Body)
+	}
+	var response feature.Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("%s: decoding response: %v", name, err)
+	}
+	return response.Enabled
+}
+
+func assertEvaluation(t *testing.T, name string, want bool) {
+	t.Helper()
+	if got := evaluate(t, name); got != want {
+		t.Errorf("%s: enabled = %v, want %v", name, got, want)
+	}
+}
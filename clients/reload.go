@@ -0,0 +1,119 @@
+package clients
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/navikt/klage-unleash-proxy/nais"
+)
+
+// reloadMu serializes reloads with each other and with Initialize.
+var reloadMu sync.Mutex
+
+// ErrNotInitialized is returned by Reload while Initialize has not finished.
+var ErrNotInitialized = errors.New("Unleash clients are still being initialized")
+
+// reloadReadyTimeout bounds how long a reload waits for each new client to be ready,
+// so an unreachable Unleash server cannot block reloads forever.
+var reloadReadyTimeout = 5 * time.Second
+
+// Reload re-reads the inbound applications, creates clients for added apps and closes clients for removed apps.
+// Existing clients are kept as they are. New clients are added as each becomes ready, and mu is only held to
+// swap the maps, so requests keep being served and Ready stays true throughout.
+// Clients that were created are added even if others failed, including those not ready within RELOAD_READY_TIMEOUT.
+// Added apps are subject to the same token validation as at startup.
+// Returns ErrNotInitialized until Initialize has finished.
+// Each reload is counted in config_reloads_total by result, and inbound_apps tracks the size of the allow-list.
+func Reload() (added []string, removed []string, err error) {
+	if !ready.Load() {
+		metrics.RecordConfigReload("not_initialized")
+		return nil, nil, ErrNotInitialized
+	}
+
+	defer func() {
+		if err != nil {
+			metrics.RecordConfigReload("error")
+		} else {
+			metrics.RecordConfigReload("success")
+		}
+	}()
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	inbound, err := nais.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desired := appsFor(inbound)
+
+	mu.RLock()
+	for _, app := range desired {
+		if _, ok := clientMap[app]; !ok {
+			added = append(added, app)
+		}
+	}
+	for app := range clientMap {
+		if !slices.Contains(desired, app) {
+			removed = append(removed, app)
+		}
+	}
+	mu.RUnlock()
+
+	for _, app := range added {
+		if err := validateAppToken(app); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Added apps are known from here on, so until their client is ready, requests for them are answered
+	// as not ready rather than as from an unknown app
+	mu.Lock()
+	inboundApps = inbound
+	mu.Unlock()
+
+	created, err := createClients(added, reloadReadyTimeout)
+
+	closing := make(map[string]*managedClient, len(removed))
+
+	mu.Lock()
+	for _, app := range removed {
+		// Close does not wait for reloads, so a shutdown may have closed and removed the client in the meantime
+		client, ok := clientMap[app]
+		if !ok {
+			continue
+		}
+		closing[app] = client
+		delete(clientMap, app)
+	}
+	mu.Unlock()
+
+	metrics.SetInboundApps(len(inbound))
+
+	for app, client := range closing {
+		slog.Info("Closing Unleash client for removed app",
+			slog.String("app_name", app),
+		)
+		client.close()
+		closeShadowClient(app)
+		metrics.UnregisterClientHealth(app)
+	}
+
+	added = slices.Collect(maps.Keys(created))
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	slog.Info("Reloaded Unleash clients",
+		slog.Any("added", added),
+		slog.Any("removed", removed),
+		slog.Int("count", len(desired)),
+	)
+
+	return added, removed, err
+}
@@ -0,0 +1,244 @@
+package clients_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/feature"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReloadAddsAndRemovesApps(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	clientstest.SetForTest(t, &env.InboundApps, "app-2,app-3")
+	added, removed, err := clients.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if !slices.Equal(added, []string{"app-3"}) {
+		t.Errorf("added = %v, want [app-3]", added)
+	}
+	if !slices.Equal(removed, []string{"app-1"}) {
+		t.Errorf("removed = %v, want [app-1]", removed)
+	}
+	if _, ok := clients.Get("app-1"); ok {
+		t.Error("removed app-1 still has a client")
+	}
+	for _, app := range []string{"app-2", "app-3"} {
+		if _, ok := clients.Get(app); !ok {
+			t.Errorf("%s has no client", app)
+		}
+	}
+	if apps := clients.InboundApps(); !slices.Equal(apps, []string{"app-2", "app-3"}) {
+		t.Errorf("InboundApps = %v, want [app-2 app-3]", apps)
+	}
+}
+
+func TestReloadGivesUpOnClientsNotReadyInTime(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1")
+
+	t.Cleanup(clients.SetReloadReadyTimeout(50 * time.Millisecond))
+	server.SetFetchDelay(time.Second)
+
+	clientstest.SetForTest(t, &env.InboundApps, "app-1,app-2")
+	added, _, err := clients.Reload()
+	if err == nil || !strings.Contains(err.Error(), "not ready within") {
+		t.Errorf("Reload = %v, want a not ready error", err)
+	}
+	if len(added) > 0 {
+		t.Errorf("added = %v, want none", added)
+	}
+	if _, ok := clients.Get("app-2"); ok {
+		t.Error("app-2 has a client that was not ready in time")
+	}
+	if _, ok := clients.Get("app-1"); !ok {
+		t.Error("existing app-1 lost its client")
+	}
+}
+
+func TestReloadValidatesTokensOfAddedApps(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1")
+
+	clientstest.SetForTest(t, &env.UnleashServerAPIToken, "*:*.admin-secret")
+	clientstest.SetForTest(t, &env.InboundApps, "app-1,app-2")
+	if _, _, err := clients.Reload(); err == nil {
+		t.Error("Reload added an app with an admin token")
+	}
+	if _, ok := clients.Get("app-2"); ok {
+		t.Error("app-2 has a client with an admin token")
+	}
+}
+
+func TestReloadBeforeInitialize(t *testing.T) {
+	t.Cleanup(clients.SetReady(false))
+
+	if _, _, err := clients.Reload(); !errors.Is(err, clients.ErrNotInitialized) {
+		t.Errorf("Reload = %v, want %v", err, clients.ErrNotInitialized)
+	}
+}
+
+func TestInitializeServesReadyAppsWhenOthersTimeOut(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	t.Cleanup(clients.SetInitReadyTimeout(200 * time.Millisecond))
+
+	// The first fetch is slow, so the first client is not ready in time, while the others are
+	server.SetFetchDelay(time.Second)
+	go func() {
+		for server.Fetches() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		server.SetFetchDelay(0)
+	}()
+	t.Cleanup(clients.SetInitConcurrency(1))
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	readyApps, notReadyApps := clients.ReadyApps()
+	if len(readyApps) != 1 || len(notReadyApps) != 1 {
+		t.Errorf("ReadyApps = %v, %v, want one ready and one not ready app", readyApps, notReadyApps)
+	}
+}
+
+func TestReloadRecordsMetrics(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1")
+
+	if got := testutil.ToFloat64(metrics.InboundApps); got != 1 {
+		t.Errorf("inbound_apps = %v after Initialize, want 1", got)
+	}
+
+	successes := metrics.ConfigReloadsTotal.WithLabelValues("success")
+	failures := metrics.ConfigReloadsTotal.WithLabelValues("error")
+	notInitialized := metrics.ConfigReloadsTotal.WithLabelValues("not_initialized")
+	before := map[string]float64{
+		"success":         testutil.ToFloat64(successes),
+		"error":           testutil.ToFloat64(failures),
+		"not_initialized": testutil.ToFloat64(notInitialized),
+	}
+
+	clientstest.SetForTest(t, &env.InboundApps, "app-1,app-2,app-3")
+	if _, _, err := clients.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := testutil.ToFloat64(successes) - before["success"]; got != 1 {
+		t.Errorf("config_reloads_total{result=success} increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.InboundApps); got != 3 {
+		t.Errorf("inbound_apps = %v after reload, want 3", got)
+	}
+
+	// A rejected token fails the reload before the allow-list is replaced
+	clientstest.SetForTest(t, &env.UnleashServerAPIToken, "*:*.admin-secret")
+	clientstest.SetForTest(t, &env.InboundApps, "app-1,app-4")
+	if _, _, err := clients.Reload(); err == nil {
+		t.Fatal("Reload added an app with an admin token")
+	}
+	if got := testutil.ToFloat64(failures) - before["error"]; got != 1 {
+		t.Errorf("config_reloads_total{result=error} increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.InboundApps); got != 3 {
+		t.Errorf("inbound_apps = %v after a failed reload, want 3", got)
+	}
+
+	restore := clients.SetReady(false)
+	_, _, err := clients.Reload()
+	restore()
+	if !errors.Is(err, clients.ErrNotInitialized) {
+		t.Errorf("Reload = %v, want %v", err, clients.ErrNotInitialized)
+	}
+	if got := testutil.ToFloat64(notInitialized) - before["not_initialized"]; got != 1 {
+		t.Errorf("config_reloads_total{result=not_initialized} increased by %v, want 1", got)
+	}
+}
+
+func TestReloadConcurrentWithClose(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	clientstest.StartClients(t, server, "app-1", "app-2")
+
+	// Adding app-3 keeps the reload busy between choosing the apps to remove and removing them
+	t.Cleanup(clients.SetReloadReadyTimeout(time.Second))
+	server.SetFetchDelay(100 * time.Millisecond)
+	clientstest.SetForTest(t, &env.InboundApps, "app-3")
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := clients.Reload()
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	clients.Close()
+
+	if err := <-done; err != nil {
+		t.Errorf("Reload: %v", err)
+	}
+	for _, app := range []string{"app-1", "app-2"} {
+		if _, ok := clients.Get(app); ok {
+			t.Errorf("%s still has a client after Close", app)
+		}
+	}
+}
+
+func TestReloadAnswersAddedAppsAsNotReadyUntilTheirClientIs(t *testing.T) {
+	server := clientstest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetToggle("new-ui", true)
+	clientstest.StartClients(t, server, "app-1")
+	feature.InitTracer()
+
+	t.Cleanup(clients.SetReloadReadyTimeout(5 * time.Second))
+	server.SetFetchDelay(300 * time.Millisecond)
+	clientstest.SetForTest(t, &env.InboundApps, "app-1,app-2")
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := clients.Reload()
+		done <- err
+	}()
+
+	check := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		feature.Handler(w, httptest.NewRequest(http.MethodPost, feature.PathPrefix+"new-ui", strings.NewReader(`{"appName":"app-2"}`)))
+		return w
+	}
+
+	clientstest.Eventually(t, func() bool { return clients.IsValidApp("app-2") })
+	w := check()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d before the client is ready, want %d: %s", w.Code, http.StatusServiceUnavailable, w.Body)
+	}
+	var response feature.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "app_not_ready" {
+		t.Errorf("code %q, want app_not_ready", response.Code)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if w := check(); w.Code != http.StatusOK {
+		t.Errorf("status %d once the client is ready, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"time"
+)
+
+// SDKInfo describes an app's Unleash SDK client, as registered with the Unleash server.
+type SDKInfo struct {
+	// Registered is false until the client has registered, in which case only RefreshInterval is known.
+	Registered      bool      `json:"registered"`
+	SDKVersion      string    `json:"sdk_version,omitempty"`
+	InstanceID      string    `json:"instance_id,omitempty"`
+	Strategies      []string  `json:"strategies,omitempty"`
+	Started         time.Time `json:"started,omitzero"`
+	RefreshInterval string    `json:"refresh_interval"`
+	MetricsInterval string    `json:"metrics_interval,omitempty"`
+}
+
+// SDKInfos returns the SDK info of every app's client, keyed by app name.
+// The data is captured from the client's registration with the Unleash server.
+func SDKInfos() map[string]SDKInfo {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	infos := make(map[string]SDKInfo, len(clientMap))
+	for app, client := range clientMap {
+		info := SDKInfo{RefreshInterval: refreshInterval.String()}
+		if data, ok := client.listener.Registration(); ok {
+			info.Registered = true
+			info.SDKVersion = data.SDKVersion
+			info.InstanceID = data.InstanceID
+			info.Strategies = data.Strategies
+			info.Started = data.Started
+			// The SDK reports the metrics interval in whole seconds
+			info.MetricsInterval = (time.Duration(data.Interval) * time.Second).String()
+		}
+		infos[app] = info
+	}
+
+	return infos
+}
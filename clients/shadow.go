@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Unleash/unleash-go-sdk/v5"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/logging"
+)
+
+// shadowClients are the Unleash clients fetching toggles for SHADOW_ENVIRONMENT, keyed by app.
+// Toggle configuration is fetched per environment, so shadow evaluations need clients of their own.
+// It is guarded by mu.
+var shadowClients = make(map[string]*managedClient)
+
+// shadowEvaluators replace the shadow clients of apps, see RegisterShadowEvaluator. It is guarded by mu.
+var shadowEvaluators = make(map[string]Evaluator)
+
+// checkShadowToken verifies that SHADOW_API_TOKEN is set when SHADOW_ENVIRONMENT is, and is scoped to it.
+func checkShadowToken() error {
+	if env.ShadowEnvironment == "" {
+		return nil
+	}
+	if env.ShadowAPIToken == "" {
+		return fmt.Errorf("SHADOW_API_TOKEN is required with SHADOW_ENVIRONMENT")
+	}
+	if err := ValidateToken(env.ShadowAPIToken); err != nil {
+		return fmt.Errorf("SHADOW_API_TOKEN: %w", err)
+	}
+	if tokenEnv, ok := tokenEnvironment(env.ShadowAPIToken); ok && tokenEnv != "*" && tokenEnv != env.ShadowEnvironment {
+		return fmt.Errorf("SHADOW_ENVIRONMENT %q does not match the SHADOW_API_TOKEN's environment %q", env.ShadowEnvironment, tokenEnv)
+	}
+	return nil
+}
+
+// startShadowClient creates the shadow client for an app, if SHADOW_ENVIRONMENT is set, without waiting for it to be ready.
+// Shadow evaluations are skipped until it is. It reports no usage metrics, so Unleash only sees real evaluations.
+// A shadow client that cannot be created is logged and skipped, since it never affects responses.
+func startShadowClient(app string) {
+	if env.ShadowEnvironment == "" {
+		return
+	}
+
+	storage := &featureStorage{}
+	listener := logging.NewSlogListener(app, instanceID, env.ShadowEnvironment, url)
+
+	client, err := unleash.NewClient(
+		unleash.WithListener(listener),
+		unleash.WithAppName(app),
+		unleash.WithInstanceId(instanceID),
+		unleash.WithUrl(url),
+		unleash.WithCustomHeaders(customHeaders.headers(app, env.ShadowAPIToken)),
+		unleash.WithRefreshInterval(refreshInterval),
+		unleash.WithStorage(storage),
+		unleash.WithDisableMetrics(true),
+	)
+	if err != nil {
+		slog.Warn("Failed to create shadow Unleash client for "+app,
+			slog.String("app_name", app),
+			slog.String("environment", env.ShadowEnvironment),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	mu.Lock()
+	shadowClients[app] = &managedClient{client: client, listener: listener, storage: storage}
+	mu.Unlock()
+}
+
+// closeShadowClient closes and removes the shadow client for an app, if it has one.
+func closeShadowClient(app string) {
+	mu.Lock()
+	client, ok := shadowClients[app]
+	delete(shadowClients, app)
+	mu.Unlock()
+
+	if ok {
+		client.close()
+	}
+}
+
+// GetShadow returns the Evaluator for the given app's shadow client, evaluating with the toggles of SHADOW_ENVIRONMENT,
+// or the one registered with RegisterShadowEvaluator, if any.
+// Returns nil and false if shadow evaluation is disabled, or the app's shadow client is not ready.
+func GetShadow(appName string) (Evaluator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if evaluator, ok := shadowEvaluators[appName]; ok {
+		return evaluator, true
+	}
+	client, ok := shadowClients[appName]
+	if !ok || !client.listener.Ready() {
+		return nil, false
+	}
+	return sdkEvaluator{c: client}, true
+}
+
+// RegisterShadowEvaluator makes GetShadow return the given Evaluator for the app instead of its shadow client,
+// e.g. a clientstest.Evaluator in shadow evaluation tests.
+func RegisterShadowEvaluator(appName string, evaluator Evaluator) {
+	mu.Lock()
+	defer mu.Unlock()
+	shadowEvaluators[appName] = evaluator
+}
+
+// UnregisterShadowEvaluator removes the Evaluator registered for the app with RegisterShadowEvaluator.
+func UnregisterShadowEvaluator(appName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(shadowEvaluators, appName)
+}
@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"time"
+)
+
+// ClientStatus summarizes the state of an app's Unleash client, as observed by its listener.
+type ClientStatus struct {
+	// Exists is false while the client is being created, or if creating it failed.
+	Exists bool `json:"exists"`
+	Ready  bool `json:"ready"`
+	// LastSuccess is the last successful response from Unleash, including unchanged toggle fetches.
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastError   time.Time `json:"last_error,omitzero"`
+	// LastErrorMessage is the message of the last error, which may since have been resolved.
+	LastErrorMessage string `json:"last_error_message,omitempty"`
+	// InstanceID is the instance ID the client registered with, empty until it has registered.
+	InstanceID string `json:"instance_id,omitempty"`
+}
+
+// Status returns the status of the client of every inbound app, and of the default app in permissive mode, keyed by app name.
+func Status() map[string]ClientStatus {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	apps := appsFor(inboundApps)
+	statuses := make(map[string]ClientStatus, len(apps))
+	for _, app := range apps {
+		client, ok := clientMap[app]
+		if !ok {
+			statuses[app] = ClientStatus{}
+			continue
+		}
+
+		status := ClientStatus{
+			Exists:           true,
+			Ready:            client.listener.Ready(),
+			LastSuccess:      client.listener.LastSuccess(),
+			LastError:        client.listener.LastError(),
+			LastErrorMessage: client.listener.LastErrorMessage(),
+		}
+		if data, ok := client.listener.Registration(); ok {
+			status.InstanceID = data.InstanceID
+		}
+		statuses[app] = status
+	}
+
+	return statuses
+}
@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"sync"
+
+	"github.com/Unleash/unleash-go-sdk/v5"
+)
+
+// featureStorage is the SDK's default storage of fetched features, which the proxy can also query.
+// The SDK only guards its storage with its own repository lock, so featureStorage has a lock of its own.
+type featureStorage struct {
+	mu      sync.RWMutex
+	storage unleash.DefaultStorage
+}
+
+var _ unleash.Storage = (*featureStorage)(nil)
+
+func (s *featureStorage) Init(backupPath, appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storage.Init(backupPath, appName)
+}
+
+func (s *featureStorage) Reset(data map[string]any, persist bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storage.Reset(data, persist)
+}
+
+func (s *featureStorage) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storage.Load()
+}
+
+func (s *featureStorage) Persist() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.storage.Persist()
+}
+
+func (s *featureStorage) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.storage.Get(key)
+}
+
+func (s *featureStorage) List() []any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.storage.List()
+}
+
+// known reports whether the feature was in the last toggles fetched from Unleash.
+func (s *featureStorage) known(name string) bool {
+	_, ok := s.Get(name)
+	return ok
+}
@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// appTokens maps app names to their own Unleash API token, from UNLEASH_APP_API_TOKENS.
+// It is set by Initialize and not modified afterwards.
+var appTokens map[string]string
+
+// parseAppTokens parses UNLEASH_APP_API_TOKENS (app to Unleash API token) from JSON.
+// The error never includes the variable's value, since it holds secrets.
+func parseAppTokens() (map[string]string, error) {
+	if env.UnleashAppAPITokens == "" {
+		return nil, nil
+	}
+
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(env.UnleashAppAPITokens), &parsed); err != nil {
+		return nil, errors.New("invalid UNLEASH_APP_API_TOKENS: must be a JSON object of app name to token")
+	}
+
+	return parsed, nil
+}
+
+// token returns the Unleash API token for the given app.
+// Falls back to the shared UNLEASH_SERVER_API_TOKEN when the app has no token of its own.
+func token(appName string) string {
+	if t, ok := appTokens[appName]; ok && t != "" {
+		return t
+	}
+	return env.UnleashServerAPIToken
+}
+
+// RedactToken returns a token safe for logging, keeping only its "<project>:<environment>." scope.
+func RedactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	scope, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return "***"
+	}
+
+	return scope + ".***"
+}
+
+// validateTokens validates the shared token and every per-app token, and checks each app's token
+// against the app's Unleash environment.
+func validateTokens(apps []string) error {
+	if err := ValidateToken(env.UnleashServerAPIToken); err != nil {
+		return err
+	}
+
+	for app, t := range appTokens {
+		if err := ValidateToken(t); err != nil {
+			return fmt.Errorf("token for %s: %w", app, err)
+		}
+	}
+
+	for _, app := range apps {
+		if err := checkTokenEnvironment(app, token(app)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAppToken checks the token of an app's client as validateTokens does at startup,
+// for apps added after startup.
+func validateAppToken(app string) error {
+	if err := ValidateToken(token(app)); err != nil {
+		return fmt.Errorf("token for %s: %w", app, err)
+	}
+	return checkTokenEnvironment(app, token(app))
+}
+
+// ValidateToken checks that an Unleash API token is not an admin or personal access token.
+// The proxy only evaluates features, so it should run with a client token scoped to
+// a single environment, shaped like "<project>:<environment>.<secret>".
+// Admin tokens ("*:*.<secret>") and personal access tokens ("user:<secret>") are rejected.
+func ValidateToken(token string) error {
+	if strings.HasPrefix(token, "*:*.") {
+		return errors.New("Unleash API token is an admin token, use a client token instead")
+	}
+	if strings.HasPrefix(token, "user:") {
+		return errors.New("Unleash API token is a personal access token, use a client token instead")
+	}
+	return nil
+}
+
+// tokenEnvironment returns the environment a token is scoped to,
+// if it is shaped like "<project>:<environment>.<secret>".
+func tokenEnvironment(token string) (string, bool) {
+	_, scope, ok := strings.Cut(token, ":")
+	if !ok {
+		return "", false
+	}
+
+	environment, _, ok := strings.Cut(scope, ".")
+	if !ok || environment == "" {
+		return "", false
+	}
+
+	return environment, true
+}
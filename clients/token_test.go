@@ -0,0 +1,63 @@
+package clients_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+func TestValidateToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "client token", token: "default:production.abc123"},
+		{name: "all projects client token", token: "*:production.abc123"},
+		{name: "unscoped token", token: "abc123"},
+		{name: "empty", token: ""},
+		{name: "admin token", token: "*:*.abc123", wantErr: "admin token"},
+		{name: "personal access token", token: "user:abc123", wantErr: "personal access token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := clients.ValidateToken(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateToken(%q): %v", tt.token, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateToken(%q) = %v, want an error mentioning %s", tt.token, err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "abc123") {
+				t.Errorf("ValidateToken(%q) = %v, leaks the secret", tt.token, err)
+			}
+		})
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "", want: ""},
+		{token: "default:production.abc123", want: "default:production.***"},
+		{token: "*:*.abc123", want: "*:*.***"},
+		{token: "user:abc123", want: "***"},
+		{token: "abc123", want: "***"},
+		{token: "default:production.abc.123", want: "default:production.***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if got := clients.RedactToken(tt.token); got != tt.want {
+				t.Errorf("RedactToken(%q) = %q, want %q", tt.token, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package clients
+
+import (
+	"net/http"
+
+	"github.com/navikt/klage-unleash-proxy/logging"
+)
+
+// contactTransport records every successful response from the Unleash server on the client's listener.
+// The SDK fires no event when a toggle fetch returns 304 Not Modified, and sends no metrics while no features
+// are evaluated, so an idle client that reaches Unleash fine would otherwise look stale.
+type contactTransport struct {
+	base     http.RoundTripper
+	listener *logging.SlogListener
+}
+
+func (t contactTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && (resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode == http.StatusNotModified) {
+		t.listener.RecordContact()
+	}
+	return resp, err
+}
+
+// newHTTPClient returns the HTTP client for an app's Unleash client, recording successful contact on the listener.
+func newHTTPClient(listener *logging.SlogListener) *http.Client {
+	return &http.Client{
+		Transport: contactTransport{base: http.DefaultTransport, listener: listener},
+	}
+}
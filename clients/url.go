@@ -0,0 +1,14 @@
+package clients
+
+import "strings"
+
+// apiURL resolves the Unleash API url from UNLEASH_SERVER_API_URL.
+// Trailing slashes are trimmed, and /api is only appended if the url does not already end with it,
+// so both https://unleash.example and https://unleash.example/api/ resolve to https://unleash.example/api.
+func apiURL(serverURL string) string {
+	trimmed := strings.TrimRight(serverURL, "/")
+	if strings.HasSuffix(trimmed, "/api") {
+		return trimmed
+	}
+	return trimmed + "/api"
+}
@@ -0,0 +1,32 @@
+package clients_test
+
+import (
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+func TestAPIURL(t *testing.T) {
+	tests := []struct {
+		serverURL string
+		want      string
+	}{
+		{serverURL: "https://unleash.example", want: "https://unleash.example/api"},
+		{serverURL: "https://unleash.example/", want: "https://unleash.example/api"},
+		{serverURL: "https://unleash.example/api", want: "https://unleash.example/api"},
+		{serverURL: "https://unleash.example/api/", want: "https://unleash.example/api"},
+		{serverURL: "https://unleash.example/api//", want: "https://unleash.example/api"},
+		{serverURL: "https://unleash.example/klage/api", want: "https://unleash.example/klage/api"},
+		{serverURL: "https://unleash.example/klage", want: "https://unleash.example/klage/api"},
+		{serverURL: "https://unleash.example/apis", want: "https://unleash.example/apis/api"},
+		{serverURL: "https://api.unleash.example", want: "https://api.unleash.example/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.serverURL, func(t *testing.T) {
+			if got := clients.APIURL(tt.serverURL); got != tt.want {
+				t.Errorf("APIURL(%q) = %q, want %q", tt.serverURL, got, tt.want)
+			}
+		})
+	}
+}
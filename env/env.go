@@ -1,8 +1,11 @@
 package env
 
 import (
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // NAIS environment variables
@@ -11,20 +14,155 @@ var NaisClusterName = os.Getenv("NAIS_CLUSTER_NAME")
 var NaisNamespace = os.Getenv("NAIS_NAMESPACE")
 var NaisPodName = os.Getenv("NAIS_POD_NAME")
 var NaisAppImage = os.Getenv("NAIS_APP_IMAGE")
+var NaisAppSources = List(os.Getenv("NAIS_APP_SOURCES"))
+var InboundApps = os.Getenv("INBOUND_APPS")
+var InboundAppsFile = os.Getenv("INBOUND_APPS_FILE")
 var _, AppVersion, _ = strings.Cut(NaisAppImage, ":")
 
 // Unleash environment variables
 var UnleashServerAPIURL = os.Getenv("UNLEASH_SERVER_API_URL")
 var UnleashServerAPIToken = os.Getenv("UNLEASH_SERVER_API_TOKEN")
 var UnleashServerAPIEnv = os.Getenv("UNLEASH_SERVER_API_ENV")
+var UnleashAppAPITokens = os.Getenv("UNLEASH_APP_API_TOKENS")
+var UnleashAppEnvironments = os.Getenv("UNLEASH_APP_ENVIRONMENTS")
+var UnleashCustomHeaders = os.Getenv("UNLEASH_CUSTOM_HEADERS")
+var UnleashAppCustomHeaders = os.Getenv("UNLEASH_APP_CUSTOM_HEADERS")
+var AppEnforcement = os.Getenv("APP_ENFORCEMENT")
+var SkipUnleashDNSCheck = os.Getenv("SKIP_UNLEASH_DNS_CHECK") == "true"
 
 // OpenTelemetry environment variables
 var OtelServiceName = os.Getenv("OTEL_SERVICE_NAME")
 var OtelServiceVersion = os.Getenv("OTEL_SERVICE_VERSION")
 var OtelExporterOTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+var OtelExporterOTLPInsecure = os.Getenv("OTEL_EXPORTER_OTLP_INSECURE")
+var OtelTracesEnabled = os.Getenv("OTEL_TRACES_ENABLED") != "false"
+var OtelMetricsEnabled = os.Getenv("OTEL_METRICS_ENABLED") != "false"
+var OtelLogsEnabled = os.Getenv("OTEL_LOGS_ENABLED") == "true"
+var OtelTracesSamplerArg = os.Getenv("OTEL_TRACES_SAMPLER_ARG")
+
+// Evaluation environment variables
+var KillSwitchFeature = os.Getenv("KILL_SWITCH_FEATURE")
+var KillSwitchDefault = os.Getenv("KILL_SWITCH_DEFAULT")
+var KillSwitchTTL = os.Getenv("KILL_SWITCH_TTL")
+var ForceEnabledNavIdents = List(os.Getenv("FORCE_ENABLED_NAV_IDENTS"))
+var DefaultNavIdent = os.Getenv("DEFAULT_NAV_IDENT")
+var DefaultAppNavIdents = os.Getenv("DEFAULT_APP_NAV_IDENTS")
+var FeatureForcedOn = os.Getenv("FEATURE_FORCED_ON")
+var FeatureForcedOff = os.Getenv("FEATURE_FORCED_OFF")
+var SlowFeatureRequestThreshold = os.Getenv("SLOW_FEATURE_REQUEST_THRESHOLD")
+var FeatureRequestTimeout = os.Getenv("FEATURE_REQUEST_TIMEOUT")
+var FeatureCacheTTL = os.Getenv("FEATURE_CACHE_TTL")
+var StaleResultsAfter = os.Getenv("STALE_RESULTS_AFTER")
+var RequireKnownFeature = os.Getenv("REQUIRE_KNOWN_FEATURE") == "true"
+var DecisionSinkURL = os.Getenv("DECISION_SINK_URL")
+var DecisionUserKey = os.Getenv("DECISION_USER_KEY")
+var MaxRequestBodyBytes = os.Getenv("MAX_REQUEST_BODY_BYTES")
+var MaxRequestProperties = os.Getenv("MAX_REQUEST_PROPERTIES")
+var MaxBatchSize = os.Getenv("MAX_BATCH_SIZE")
+var ClientIPHeader = os.Getenv("CLIENT_IP_HEADER")
+var AnonymousSessionCookie = os.Getenv("ANONYMOUS_SESSION_COOKIE")
+var DefaultProperties = os.Getenv("DEFAULT_PROPERTIES")
+var ShadowEnvironment = os.Getenv("SHADOW_ENVIRONMENT")
+var ShadowAPIToken = os.Getenv("SHADOW_API_TOKEN")
+var ShadowConcurrency = os.Getenv("SHADOW_CONCURRENCY")
+var DeprecatedFeatures = List(os.Getenv("DEPRECATED_FEATURES"))
+var InvertedFeatures = List(os.Getenv("INVERTED_FEATURES"))
+var FeatureNameSeparator = os.Getenv("FEATURE_NAME_SEPARATOR")
+var FeatureNameAliasSeparators = os.Getenv("FEATURE_NAME_ALIAS_SEPARATORS")
+var EvaluationRetryDelay = os.Getenv("EVALUATION_RETRY_DELAY")
+
+// Stats environment variables
+var StatsWindow = os.Getenv("STATS_WINDOW")
+var StatsMaxFeatures = os.Getenv("STATS_MAX_FEATURES")
+
+// OpenTelemetry span sampling environment variables
+var UnleashSpanSampleRatio = os.Getenv("UNLEASH_SPAN_SAMPLE_RATIO")
 
 // Server environment variables
 var Port = os.Getenv("PORT")
+var LogLevel = os.Getenv("LOG_LEVEL")
+var LogRequestFields = List(os.Getenv("LOG_REQUEST_FIELDS"))
+var AdminToken = os.Getenv("ADMIN_TOKEN")
+var TrustedProxyCIDRs = List(os.Getenv("TRUSTED_PROXY_CIDRS"))
+var EnableH2C = os.Getenv("ENABLE_H2C") == "true"
+var ServerReadHeaderTimeout = os.Getenv("SERVER_READ_HEADER_TIMEOUT")
+var ServerReadTimeout = os.Getenv("SERVER_READ_TIMEOUT")
+var ServerWriteTimeout = os.Getenv("SERVER_WRITE_TIMEOUT")
+var ServerIdleTimeout = os.Getenv("SERVER_IDLE_TIMEOUT")
+var ShutdownTimeout = os.Getenv("SHUTDOWN_TIMEOUT")
+var InitConcurrency = os.Getenv("INIT_CONCURRENCY")
+var ReloadReadyTimeout = os.Getenv("RELOAD_READY_TIMEOUT")
+var InitReadyTimeout = os.Getenv("INIT_READY_TIMEOUT")
+var ReadinessShedThreshold = os.Getenv("READINESS_SHED_THRESHOLD")
+var ReadinessStaleThreshold = os.Getenv("READINESS_STALE_THRESHOLD")
 
 const DefaultServiceName = "klage-unleash-proxy"
 const DefaultPort = "8080"
+
+// List splits a comma-separated environment variable value into its trimmed, non-empty entries.
+func List(value string) []string {
+	var list []string
+	for entry := range strings.SplitSeq(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// Int parses an integer environment variable value.
+// It returns the fallback if the value is empty, and logs a warning and returns the fallback if it is invalid.
+func Int(name, value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer in "+name+", using default",
+			slog.String("value", value),
+			slog.Int("default", fallback),
+		)
+		return fallback
+	}
+
+	return parsed
+}
+
+// Ratio parses a ratio environment variable value between 0.0 and 1.0.
+// It returns the fallback if the value is empty, and logs a warning and returns the fallback if it is invalid.
+func Ratio(name, value string, fallback float64) float64 {
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		slog.Warn("Invalid ratio in "+name+", must be between 0.0 and 1.0, using default",
+			slog.String("value", value),
+			slog.Float64("default", fallback),
+		)
+		return fallback
+	}
+
+	return parsed
+}
+
+// Duration parses a duration environment variable value, e.g. "5s".
+// It returns the fallback if the value is empty, and logs a warning and returns the fallback if it is invalid.
+func Duration(name, value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		slog.Warn("Invalid duration in "+name+", using default",
+			slog.String("value", value),
+			slog.Duration("default", fallback),
+		)
+		return fallback
+	}
+
+	return parsed
+}
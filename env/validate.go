@@ -0,0 +1,84 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// naisAppSources are the valid NAIS_APP_SOURCES entries, the nais package's Source constants.
+var naisAppSources = []string{"inbound", "outbound", "unleash"}
+
+// Validate checks that the environment variables required to reach Unleash are set,
+// and that optional variables without a fallback for invalid values are well-formed.
+// The returned error lists every problem. Optional variables with defaults, like PORT, are not checked.
+func Validate() error {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"UNLEASH_SERVER_API_URL", UnleashServerAPIURL},
+		{"UNLEASH_SERVER_API_TOKEN", UnleashServerAPIToken},
+		{"UNLEASH_SERVER_API_ENV", UnleashServerAPIEnv},
+	}
+
+	var errs []error
+	for _, variable := range required {
+		if variable.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", variable.name))
+		}
+	}
+
+	if KillSwitchDefault != "" {
+		if _, err := strconv.ParseBool(KillSwitchDefault); err != nil {
+			errs = append(errs, fmt.Errorf("KILL_SWITCH_DEFAULT must be true or false, got %q", KillSwitchDefault))
+		}
+	}
+
+	// JSON objects are parsed when their packages are initialized, which can't fail cleanly, so they are checked here
+	objects := []struct {
+		name   string
+		value  string
+		target any
+	}{
+		{"DEFAULT_PROPERTIES", DefaultProperties, new(map[string]string)},
+		{"FEATURE_FORCED_ON", FeatureForcedOn, new(map[string][]string)},
+		{"FEATURE_FORCED_OFF", FeatureForcedOff, new(map[string][]string)},
+		{"DEFAULT_APP_NAV_IDENTS", DefaultAppNavIdents, new(map[string]string)},
+	}
+	for _, object := range objects {
+		if object.value == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(object.value), object.target); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", object.name, err))
+		}
+	}
+
+	// An explicit INBOUND_APPS_FILE must not silently fall back to the embedded nais.yaml
+	if InboundAppsFile != "" && InboundApps == "" {
+		if _, err := os.ReadFile(InboundAppsFile); err != nil {
+			errs = append(errs, fmt.Errorf("INBOUND_APPS_FILE can't be read: %w", err))
+		}
+	}
+
+	// The sources are parsed when the nais package is initialized, which can't fail cleanly, so they are checked here
+	for _, source := range NaisAppSources {
+		if !slices.Contains(naisAppSources, source) {
+			errs = append(errs, fmt.Errorf("invalid NAIS_APP_SOURCES entry %q, must be one of %s", source, strings.Join(naisAppSources, ", ")))
+		}
+	}
+
+	for _, cidr := range TrustedProxyCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid TRUSTED_PROXY_CIDRS entry %q: %w", cidr, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
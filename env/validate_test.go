@@ -0,0 +1,202 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name              string
+		killSwitchDefault string
+		wantErr           string
+	}{
+		{name: "valid"},
+		{name: "kill switch default true", killSwitchDefault: "true"},
+		{name: "kill switch default false", killSwitchDefault: "false"},
+		{name: "kill switch default typo", killSwitchDefault: "ture", wantErr: "KILL_SWITCH_DEFAULT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRequired(t)
+			setForTest(t, &KillSwitchDefault, tt.killSwitchDefault)
+
+			err := Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateJSONObjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable *string
+		value    string
+		wantErr  string
+	}{
+		{name: "default properties", variable: &DefaultProperties, value: `{"platform":"nais"}`},
+		{name: "default properties malformed", variable: &DefaultProperties, value: `{"platform":`, wantErr: "DEFAULT_PROPERTIES"},
+		{name: "default properties not strings", variable: &DefaultProperties, value: `{"replicas":3}`, wantErr: "DEFAULT_PROPERTIES"},
+		{name: "forced on", variable: &FeatureForcedOn, value: `{"new-ui":["Z123456"]}`},
+		{name: "forced on malformed", variable: &FeatureForcedOn, value: `{"new-ui":"Z123456"}`, wantErr: "FEATURE_FORCED_ON"},
+		{name: "forced off malformed", variable: &FeatureForcedOff, value: `[]`, wantErr: "FEATURE_FORCED_OFF"},
+		{name: "default app navIdents", variable: &DefaultAppNavIdents, value: `{"kabal-api":"SRV123"}`},
+		{name: "default app navIdents malformed", variable: &DefaultAppNavIdents, value: `kabal-api=SRV123`, wantErr: "DEFAULT_APP_NAV_IDENTS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRequired(t)
+			setForTest(t, tt.variable, tt.value)
+
+			err := Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedProxyCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "valid", cidrs: []string{"10.0.0.0/8", "fd00::/8"}},
+		{name: "address without prefix length", cidrs: []string{"10.0.0.1"}, wantErr: true},
+		{name: "malformed", cidrs: []string{"10.0.0.0/8", "10.0.0/33"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRequired(t)
+			previous := TrustedProxyCIDRs
+			TrustedProxyCIDRs = tt.cidrs
+			t.Cleanup(func() { TrustedProxyCIDRs = previous })
+
+			err := Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "TRUSTED_PROXY_CIDRS") {
+				t.Errorf("Validate = %v, want it to mention TRUSTED_PROXY_CIDRS", err)
+			}
+		})
+	}
+}
+
+func TestValidateInboundAppsFile(t *testing.T) {
+	readable := filepath.Join(t.TempDir(), "nais.yaml")
+	if err := os.WriteFile(readable, []byte("spec: {}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		name        string
+		file        string
+		inboundApps string
+		wantErr     bool
+	}{
+		{name: "unset"},
+		{name: "readable", file: readable},
+		{name: "missing", file: missing, wantErr: true},
+		{name: "missing but INBOUND_APPS set", file: missing, inboundApps: "kabal-api", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRequired(t)
+			setForTest(t, &InboundAppsFile, tt.file)
+			setForTest(t, &InboundApps, tt.inboundApps)
+
+			err := Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "INBOUND_APPS_FILE") {
+				t.Errorf("Validate = %v, want it to mention INBOUND_APPS_FILE", err)
+			}
+		})
+	}
+}
+
+func TestValidateListsMissingVariables(t *testing.T) {
+	setForTest(t, &UnleashServerAPIURL, "")
+	setForTest(t, &UnleashServerAPIToken, "")
+	setForTest(t, &UnleashServerAPIEnv, "")
+
+	err := Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded without the required variables")
+	}
+	for _, name := range []string{"UNLEASH_SERVER_API_URL", "UNLEASH_SERVER_API_TOKEN", "UNLEASH_SERVER_API_ENV"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Validate = %v, want it to mention %s", err, name)
+		}
+	}
+}
+
+// withRequired sets the required variables for the duration of the test.
+func withRequired(t *testing.T) {
+	t.Helper()
+	setForTest(t, &UnleashServerAPIURL, "http://unleash.example/api")
+	setForTest(t, &UnleashServerAPIToken, "default:development.secret")
+	setForTest(t, &UnleashServerAPIEnv, "development")
+}
+
+// setForTest sets a variable for the duration of the test.
+func setForTest(t *testing.T, variable *string, value string) {
+	t.Helper()
+	previous := *variable
+	*variable = value
+	t.Cleanup(func() { *variable = previous })
+}
+
+func TestValidateNaisAppSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "valid", sources: []string{"inbound", "outbound", "unleash"}},
+		{name: "typo", sources: []string{"inbound", "outbond"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRequired(t)
+			previous := NaisAppSources
+			NaisAppSources = tt.sources
+			t.Cleanup(func() { NaisAppSources = previous })
+
+			err := Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "NAIS_APP_SOURCES") {
+				t.Errorf("Validate = %v, want it to mention NAIS_APP_SOURCES", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var BatchPath = "/features-batch"
+
+// maxBatchSize is the maximum number of distinct features evaluated in a single batch request.
+var maxBatchSize = 50
+
+// BatchRequest represents the JSON body for batch feature check requests.
+type BatchRequest struct {
+	Request
+	Features []string `json:"features"`
+}
+
+// UnmarshalJSON decodes a BatchRequest. The embedded Request is decoded with its own
+// UnmarshalJSON, so the batch body accepts the same keys as a single feature check.
+func (b *BatchRequest) UnmarshalJSON(data []byte) error {
+	if err := b.Request.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	var aux struct {
+		Features []string `json:"features"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	b.Features = aux.Features
+	return nil
+}
+
+// BatchResponse represents the JSON response for batch feature check requests.
+// Errors holds a message for each requested feature name that could not be evaluated.
+type BatchResponse struct {
+	Results map[string]bool   `json:"results"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	// Features holds each result with its evaluation time. Only included when requested with ?timings=true.
+	Features map[string]BatchTiming `json:"features,omitempty"`
+}
+
+// BatchTiming is the result of a feature in a batch with how long it took to evaluate, in milliseconds.
+type BatchTiming struct {
+	Enabled bool    `json:"enabled"`
+	Ms      float64 `json:"ms"`
+}
+
+// batchResult is the outcome of evaluating the features of a batch request.
+type batchResult struct {
+	response BatchResponse
+	// deprecated is whether any of the evaluated features is deprecated.
+	deprecated bool
+}
+
+// BatchHandler handles batch feature check requests.
+// It expects requests to POST /features-batch with a JSON body listing the features to evaluate for one user.
+// The body is validated like a single feature check, and the same timeout, cache and policies apply.
+// Invalid and, with REQUIRE_KNOWN_FEATURE, unknown feature names are reported in the errors map
+// instead of failing the whole batch.
+func BatchHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	ctx, span, log, end := startCheck(w, r, "featureBatchHandler")
+	defer end()
+
+	if !allowMethod(w, r, span, log, http.MethodPost) {
+		return
+	}
+
+	var req BatchRequest
+	if !decodeBody(w, r, span, log, &req) {
+		return
+	}
+
+	// Each feature is evaluated once, however often it is requested
+	req.Features = uniqueFeatures(req.Features)
+
+	span.SetAttributes(attribute.Int("request.feature_count", len(req.Features)))
+
+	if len(req.Features) == 0 {
+		span.SetStatus(codes.Error, "missing features")
+		span.SetAttributes(attribute.String("error.type", "missing_feature"))
+		log.Warn("Missing features in request body",
+			"error_type", "missing_feature",
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		metrics.RecordFeatureError("missing_feature_name")
+		writeError(w, http.StatusBadRequest, "missing_feature", "features is required in request body")
+		return
+	}
+
+	if len(req.Features) > maxBatchSize {
+		span.SetStatus(codes.Error, "batch too large")
+		span.SetAttributes(attribute.String("error.type", "batch_too_large"))
+		log.Warn(fmt.Sprintf("Too many features in batch request: %d", len(req.Features)),
+			"error_type", "batch_too_large",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"feature_count", len(req.Features),
+		)
+		metrics.RecordFeatureError("batch_too_large")
+		writeError(w, http.StatusBadRequest, "batch_too_large", fmt.Sprintf("Too many features: at most %d distinct features can be evaluated per request, got %d", maxBatchSize, len(req.Features)))
+		return
+	}
+
+	client, ok := validateRequest(w, r, span, log, &req.Request)
+	if !ok {
+		return
+	}
+
+	// The kill switch takes precedence over all evaluation, including hooks
+	killSwitch := killSwitchActive(req.AppName, client)
+	span.SetAttributes(attribute.Bool("proxy.kill_switch", killSwitch))
+
+	unleashCtx := newUnleashContext(w, r, req.Request)
+
+	// Evaluate in the background, so the request can give up on a batch that outlives its deadline
+	results := make(chan batchResult, 1)
+	go func() {
+		results <- evaluateBatch(ctx, r, log, client, req, unleashCtx, killSwitch)
+	}()
+
+	var result batchResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		abandonEvaluation(ctx, w, r, span, log, req.AppName)
+		return
+	}
+
+	if result.deprecated {
+		w.Header().Set("Deprecation", "true")
+	}
+
+	duration := time.Since(startTime)
+
+	log.Log(ctx, checkLogLevel(duration), fmt.Sprintf("Batch feature check for %s - %d features", req.AppName, len(req.Features)),
+		"results", result.response.Results,
+		"user_id", req.NavIdent,
+		"app_name", req.AppName,
+		"pod_name", req.PodName,
+		"duration", duration.Milliseconds(),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.response)
+}
+
+// uniqueFeatures returns the feature names without duplicates, in the order they were first requested.
+// All features of a batch share the same context, so a repeated name would evaluate to the same result.
+// Valid names are compared normalized, so aliases of a feature are evaluated once, under the first name requested.
+func uniqueFeatures(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		key := name
+		if IsValidName(name) {
+			key = NormalizeName(name)
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// evaluateBatch evaluates each feature of a batch request, with a child span per feature.
+// Features that cannot be evaluated are reported in the response's errors map.
+// Once ctx is done, the request has been answered without the result, so the remaining features are skipped.
+func evaluateBatch(ctx context.Context, r *http.Request, log *slog.Logger, client clients.Evaluator, req BatchRequest, unleashCtx unleashcontext.Context, killSwitch bool) batchResult {
+	result := batchResult{
+		response: BatchResponse{
+			Results: make(map[string]bool, len(req.Features)),
+		},
+	}
+
+	// Timings are opt-in, so existing callers keep getting the same response shape
+	timings := r.URL.Query().Get("timings") == "true"
+	if timings {
+		result.response.Features = make(map[string]BatchTiming, len(req.Features))
+	}
+
+	fail := func(featureName, message string) {
+		if result.response.Errors == nil {
+			result.response.Errors = make(map[string]string)
+		}
+		result.response.Errors[featureName] = message
+	}
+
+	succeed := func(featureName string, enabled bool, duration time.Duration) {
+		result.response.Results[featureName] = enabled
+		if timings {
+			result.response.Features[featureName] = BatchTiming{
+				Enabled: enabled,
+				Ms:      float64(duration) / float64(time.Millisecond),
+			}
+		}
+	}
+
+	for _, featureName := range req.Features {
+		if ctx.Err() != nil {
+			break
+		}
+
+		featureStart := time.Now()
+
+		flagCtx, flagSpan := tracer.Start(ctx, "featureBatch.evaluate",
+			trace.WithAttributes(attribute.String("feature.name", featureName)),
+		)
+
+		if !IsValidName(featureName) {
+			flagSpan.SetStatus(codes.Error, "invalid feature name")
+			flagSpan.SetAttributes(attribute.String("error.type", "invalid_feature"))
+			flagSpan.End()
+			log.Warn("Invalid feature name in batch request",
+				"error_type", "invalid_feature",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"feature", featureName,
+			)
+			metrics.RecordFeatureError("invalid_feature_name")
+			fail(featureName, "Invalid feature name: must be URL-friendly, 1-100 characters, and not '.' or '..'")
+			continue
+		}
+
+		// Results are keyed by the requested name, so callers find their flags even if the name was normalized
+		name := normalizeFeatureName(flagSpan, log, featureName)
+
+		if slices.Contains(env.DeprecatedFeatures, name) {
+			result.deprecated = true
+			flagSpan.SetAttributes(attribute.Bool("feature.deprecated", true))
+			log.Warn(fmt.Sprintf("Deprecated feature %s requested by %s", name, req.AppName),
+				"feature", name,
+				"app_name", req.AppName,
+				"pod_name", req.PodName,
+			)
+		}
+
+		if killSwitch {
+			duration := time.Since(featureStart)
+			flagSpan.SetAttributes(attribute.Bool("feature.enabled", killSwitchDefault))
+			flagSpan.End()
+			metrics.RecordFeatureRequest(killSwitchFeatureLabel, req.AppName, killSwitchDefault, duration)
+			succeed(featureName, killSwitchDefault, duration)
+			continue
+		}
+
+		enabled, known, cacheHit, stale := evaluateFeature(flagCtx, client, name, unleashCtx, req.Request)
+		duration := time.Since(featureStart)
+		if ctx.Err() != nil {
+			flagSpan.End()
+			break
+		}
+
+		if cacheHit {
+			flagSpan.SetAttributes(attribute.Bool("feature.cache_hit", true))
+			metrics.RecordFeatureCacheHit(featureLabel(name, known))
+		}
+		if stale {
+			flagSpan.SetAttributes(attribute.Bool("feature.stale", true))
+		}
+
+		// In strict environments, unknown features are an error rather than implicitly disabled, as for a single check
+		if env.RequireKnownFeature && !known {
+			flagSpan.SetStatus(codes.Error, "unknown feature")
+			flagSpan.SetAttributes(attribute.String("error.type", "unknown_feature"))
+			flagSpan.End()
+			log.Warn("Unknown feature "+name+" requested by "+req.AppName,
+				"error_type", "unknown_feature",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"feature", name,
+				"app_name", req.AppName,
+			)
+			metrics.RecordFeatureError("unknown_feature")
+			fail(featureName, "Unknown feature: "+name)
+			continue
+		}
+
+		metrics.RecordFeatureRequest(featureLabel(name, known), req.AppName, enabled, duration)
+
+		if shouldInvert(r, name) {
+			enabled = !enabled
+			flagSpan.SetAttributes(attribute.Bool("feature.inverted", true))
+			metrics.RecordFeatureInversion(featureLabel(name, known))
+		}
+
+		flagSpan.SetAttributes(
+			attribute.Bool("feature.enabled", enabled),
+			attribute.Bool("feature.known", known),
+		)
+		flagSpan.End()
+
+		succeed(featureName, enabled, duration)
+	}
+
+	return result
+}
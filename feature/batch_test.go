@@ -0,0 +1,267 @@
+package feature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// checkBatch sends a batch feature check for testApp to BatchHandler and returns the recorded response.
+func checkBatch(features ...string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{"appName": testApp, "navIdent": "Z123456", "features": features})
+	w := httptest.NewRecorder()
+	BatchHandler(w, httptest.NewRequest(http.MethodPost, BatchPath, strings.NewReader(string(body))))
+	return w
+}
+
+// withMaxBatchSize sets MAX_BATCH_SIZE for the duration of the test.
+func withMaxBatchSize(t *testing.T, size int) {
+	t.Helper()
+	previous := maxBatchSize
+	maxBatchSize = size
+	t.Cleanup(func() { maxBatchSize = previous })
+}
+
+func TestBatchHandler(t *testing.T) {
+	withEvaluator(t, map[string]bool{"batch-on": true, "batch-off": false})
+
+	w := checkBatch("batch-on", "batch-off", "batch-unknown", "not a name", "..")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	wantResults := map[string]bool{"batch-on": true, "batch-off": false, "batch-unknown": false}
+	if len(response.Results) != len(wantResults) {
+		t.Errorf("results %v, want %v", response.Results, wantResults)
+	}
+	for name, want := range wantResults {
+		if got, ok := response.Results[name]; !ok || got != want {
+			t.Errorf("results[%s] = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+
+	for _, name := range []string{"not a name", ".."} {
+		if _, ok := response.Errors[name]; !ok {
+			t.Errorf("errors %v, want an error for %q", response.Errors, name)
+		}
+		if _, ok := response.Results[name]; ok {
+			t.Errorf("results %v, want no result for the invalid %q", response.Results, name)
+		}
+	}
+}
+
+func TestBatchHandlerEvaluatesDuplicatesOnce(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"batch-a": true, "batch-b": false})
+
+	w := checkBatch("batch-a", "batch-b", "batch-a", "batch-a")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !response.Results["batch-a"] || response.Results["batch-b"] || len(response.Results) != 2 {
+		t.Errorf("results %v, want batch-a enabled and batch-b disabled", response.Results)
+	}
+	if got := evaluator.Evaluations(); got != 2 {
+		t.Errorf("%d evaluations, want one per distinct feature", got)
+	}
+}
+
+func TestBatchHandlerEvaluatesAliasesOnce(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"batch-a": true})
+	clientstest.SetForTest(t, &nameNormalizer, newNameNormalizer("-", "._"))
+
+	w := checkBatch("batch_a", "batch.a", "batch-a")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !response.Results["batch_a"] || len(response.Results) != 1 {
+		t.Errorf("results %v, want only batch_a, the first name requested, enabled", response.Results)
+	}
+	if got := evaluator.Evaluations(); got != 1 {
+		t.Errorf("%d evaluations, want one for the aliases of a feature", got)
+	}
+}
+
+func TestBatchHandlerLimitsBatchSize(t *testing.T) {
+	withEvaluator(t, map[string]bool{})
+	withMaxBatchSize(t, 3)
+
+	features := func(n int) []string {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = fmt.Sprintf("batch-%d", i)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name       string
+		features   []string
+		wantStatus int
+	}{
+		{name: "below the limit", features: features(2), wantStatus: http.StatusOK},
+		{name: "at the limit", features: features(3), wantStatus: http.StatusOK},
+		{name: "one above the limit", features: features(4), wantStatus: http.StatusBadRequest},
+		{name: "above the limit with duplicates", features: append(features(3), "batch-0", "batch-1"), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := metrics.FeatureRequestErrors.WithLabelValues("batch_too_large")
+			before := testutil.ToFloat64(errors)
+
+			w := checkBatch(tt.features...)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			wantErrors := 0.0
+			if tt.wantStatus == http.StatusBadRequest {
+				wantErrors = 1
+				var response ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("decoding response: %v", err)
+				}
+				if response.Code != "batch_too_large" || !strings.Contains(response.Error, "at most 3") {
+					t.Errorf("response %+v, want batch_too_large stating the limit", response)
+				}
+			}
+			if got := testutil.ToFloat64(errors) - before; got != wantErrors {
+				t.Errorf("feature_request_errors_total{error_type=batch_too_large} increased by %v, want %v", got, wantErrors)
+			}
+		})
+	}
+}
+
+func TestBatchHandlerRequiresFeatures(t *testing.T) {
+	withEvaluator(t, map[string]bool{})
+
+	w := checkBatch()
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "missing_feature" {
+		t.Errorf("code = %q, want missing_feature", response.Code)
+	}
+}
+
+func TestBatchHandlerRequireKnownFeature(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+	clientstest.SetForTest(t, &env.RequireKnownFeature, true)
+
+	w := checkBatch("new-ui", "retired-ui")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !response.Results["new-ui"] {
+		t.Errorf("results %v, want new-ui enabled", response.Results)
+	}
+	if _, ok := response.Results["retired-ui"]; ok {
+		t.Errorf("results %v, want no result for retired-ui", response.Results)
+	}
+	if msg := response.Errors["retired-ui"]; !strings.Contains(msg, "Unknown feature") {
+		t.Errorf("errors %v, want retired-ui reported as unknown", response.Errors)
+	}
+}
+
+func TestBatchHandlerTimings(t *testing.T) {
+	withEvaluator(t, map[string]bool{"batch-on": true, "batch-off": false})
+
+	tests := []struct {
+		name        string
+		query       string
+		wantTimings bool
+	}{
+		{name: "default", query: "", wantTimings: false},
+		{name: "requested", query: "?timings=true", wantTimings: true},
+		{name: "not true", query: "?timings=false", wantTimings: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"appName":"` + testApp + `","navIdent":"Z123456","features":["batch-on","batch-off",".."]}`
+			w := httptest.NewRecorder()
+			BatchHandler(w, httptest.NewRequest(http.MethodPost, BatchPath+tt.query, strings.NewReader(body)))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, body %s", w.Code, w.Body)
+			}
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if _, ok := raw["features"]; ok != tt.wantTimings {
+				t.Fatalf("response %s, want features %v", w.Body, tt.wantTimings)
+			}
+			if !tt.wantTimings {
+				return
+			}
+
+			var response BatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(response.Features) != 2 {
+				t.Errorf("features %v, want one entry per evaluated feature", response.Features)
+			}
+			for name, want := range map[string]bool{"batch-on": true, "batch-off": false} {
+				timing, ok := response.Features[name]
+				if !ok || timing.Enabled != want || timing.Ms < 0 {
+					t.Errorf("features[%s] = %+v, %v, want enabled %v with a duration", name, timing, ok, want)
+				}
+			}
+			if !response.Results["batch-on"] || len(response.Results) != 2 {
+				t.Errorf("results %v, want the same results as without timings", response.Results)
+			}
+		})
+	}
+}
+
+func BenchmarkBatchHandlerWithDuplicates(b *testing.B) {
+	withEvaluator(b, map[string]bool{"batch-a": true, "batch-b": false, "batch-c": true})
+
+	// A frontend listing the same gates in several places
+	features := []string{"batch-a", "batch-b", "batch-a", "batch-c", "batch-b", "batch-a", "batch-c", "batch-a"}
+	body, _ := json.Marshal(map[string]any{"appName": testApp, "navIdent": "Z123456", "features": features})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		w := httptest.NewRecorder()
+		BatchHandler(w, httptest.NewRequest(http.MethodPost, BatchPath, bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status %d, body %s", w.Code, w.Body)
+		}
+	}
+}
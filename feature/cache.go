@@ -0,0 +1,155 @@
+package feature
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+// maxCachedResults bounds the number of results in the response cache. The least recently used result is evicted first.
+const maxCachedResults = 10000
+
+// cacheTTL is how long an evaluated result is reused for identical requests. Zero disables the cache.
+var cacheTTL time.Duration
+
+// cacheKey holds every part of the Unleash context a cacheable request can vary in, see cacheable.
+type cacheKey struct {
+	appName       string
+	environment   string
+	feature       string
+	userId        string
+	podName       string
+	remoteAddress string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	enabled bool
+	known   bool
+	// expires is when the entry expires. A zero expires never does.
+	expires time.Time
+}
+
+// resultCache is an LRU cache of evaluated results.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	// order holds the entries from most to least recently used.
+	order *list.List
+}
+
+var responseCache = &resultCache{
+	entries: make(map[cacheKey]*list.Element),
+	order:   list.New(),
+}
+
+// get returns the cached result for the key, if it has not expired.
+func (c *resultCache) get(key cacheKey, now time.Time) (entry cacheEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	entry = *element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+// put stores a result for the key, evicting the least recently used result if the cache is full.
+func (c *resultCache) put(entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = &entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= maxCachedResults {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	c.entries[entry.key] = c.order.PushFront(&entry)
+}
+
+// removeApp removes every cached result of the app.
+func (c *resultCache) removeApp(appName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if key.appName == appName {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// keyable reports whether the request's result can be cached under a cacheKey.
+// The key holds app, environment, feature, user, podName and remote address, so requests that set
+// any other part of the evaluation context, or have an anonymous session, are always evaluated.
+func keyable(req Request, unleashCtx unleashcontext.Context) bool {
+	return req.UnleashContext == nil &&
+		req.CurrentTime == "" &&
+		req.Seed == "" &&
+		len(req.Properties) == 0 &&
+		unleashCtx.SessionId == ""
+}
+
+// cacheable reports whether the request's result may be served from the response cache.
+func cacheable(req Request, unleashCtx unleashcontext.Context) bool {
+	return cacheTTL > 0 && keyable(req, unleashCtx)
+}
+
+// newCacheKey returns the cache key of a keyable request's evaluation of the feature.
+func newCacheKey(featureName string, unleashCtx unleashcontext.Context) cacheKey {
+	return cacheKey{
+		appName:       unleashCtx.AppName,
+		environment:   unleashCtx.Environment,
+		feature:       featureName,
+		userId:        unleashCtx.UserId,
+		podName:       unleashCtx.Properties["podName"],
+		remoteAddress: unleashCtx.RemoteAddress,
+	}
+}
+
+// evaluateCached evaluates a feature in the SDK like evaluate, reusing a recent result for an identical request
+// if caching is enabled. It also reports whether the result came from the cache.
+// Only the SDK evaluation is cached, so hooks, stats, flip tracking and decisions apply to every request.
+func evaluateCached(ctx context.Context, client clients.Evaluator, featureName string, unleashCtx unleashcontext.Context, req Request) (enabled bool, known bool, hit bool) {
+	if !cacheable(req, unleashCtx) {
+		enabled, known = evaluateTraced(ctx, client, featureName, unleashCtx, req)
+		return enabled, known, false
+	}
+
+	key := newCacheKey(featureName, unleashCtx)
+	if entry, ok := responseCache.get(key, time.Now()); ok {
+		return entry.enabled, entry.known, true
+	}
+
+	enabled, known = evaluateTraced(ctx, client, featureName, unleashCtx, req)
+	responseCache.put(cacheEntry{
+		key:     key,
+		enabled: enabled,
+		known:   known,
+		expires: time.Now().Add(cacheTTL),
+	})
+
+	return enabled, known, false
+}
@@ -0,0 +1,148 @@
+package feature
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/logging"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/navikt/klage-unleash-proxy/trust"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startCheck starts handling a feature, variant or batch request. It counts the request in flight,
+// sets the version headers, applies FEATURE_REQUEST_TIMEOUT and starts the handler span with the given name.
+// The returned end function must be deferred by the handler.
+func startCheck(w http.ResponseWriter, r *http.Request, spanName string) (context.Context, trace.Span, *slog.Logger, func()) {
+	metrics.FeatureRequestsInFlight.Set(float64(inFlight.Add(1)))
+
+	// Add version headers to all responses
+	w.Header().Set("Server", serverHeader)
+	w.Header().Set("App-Version", env.AppVersion)
+
+	ctx := r.Context()
+	cancel := context.CancelFunc(func() {})
+	if requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+	}
+
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.path", r.URL.Path),
+		),
+	)
+
+	// Trusted callers can request Debug logging for this request only
+	if r.Header.Get("X-Debug") == "true" && trust.FromTrustedProxy(r) {
+		ctx = logging.WithVerbose(ctx)
+		span.SetAttributes(attribute.Bool("request.debug", true))
+	}
+
+	end := func() {
+		span.End()
+		cancel()
+		metrics.FeatureRequestsInFlight.Set(float64(inFlight.Add(-1)))
+	}
+
+	return ctx, span, logging.FromContext(ctx), end
+}
+
+// allowMethod reports whether the request method is one of allow, a comma-separated Allow header.
+// Otherwise it responds 405 with the Allow header.
+func allowMethod(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, allow string) bool {
+	if slices.Contains(strings.Split(allow, ", "), r.Method) {
+		return true
+	}
+
+	w.Header().Set("Allow", allow)
+	span.SetStatus(codes.Error, "method not allowed")
+	span.SetAttributes(attribute.String("error.type", "method_not_allowed"))
+	log.Warn("Method not allowed",
+		"error_type", "method_not_allowed",
+		"method", r.Method,
+		"path", r.URL.Path,
+	)
+	metrics.RecordFeatureError("method_not_allowed")
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	return false
+}
+
+// checkFeatureName validates the feature name of a single feature or variant request and returns it normalized.
+// Otherwise it responds 400.
+func checkFeatureName(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, featureName string) (string, bool) {
+	span.SetAttributes(attribute.String("feature.name", featureName))
+	// The server span is named after the route template, so it needs the feature name as an attribute
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("feature.name", featureName))
+
+	// Validate feature name according to Unleash rules
+	if !IsValidName(featureName) {
+		span.SetStatus(codes.Error, "invalid feature name")
+		span.SetAttributes(attribute.String("error.type", "invalid_feature"))
+		log.Warn("Invalid feature name",
+			"error_type", "invalid_feature",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"feature", featureName,
+		)
+		metrics.RecordFeatureError("invalid_feature_name")
+		writeError(w, http.StatusBadRequest, "invalid_feature", "Invalid feature name: must be URL-friendly, 1-100 characters, and not '.' or '..'")
+		return "", false
+	}
+
+	return normalizeFeatureName(span, log, featureName), true
+}
+
+// normalizeFeatureName normalizes the separators of a valid feature name, recording a rewritten name on the span.
+// Only valid names may be normalized, so call it after IsValidName.
+func normalizeFeatureName(span trace.Span, log *slog.Logger, featureName string) string {
+	normalized := NormalizeName(featureName)
+	if normalized != featureName {
+		log.Info("Normalized feature name "+featureName+" to "+normalized,
+			"feature", normalized,
+			"original_feature", featureName,
+		)
+		span.SetAttributes(
+			attribute.String("feature.name", normalized),
+			attribute.String("feature.original_name", featureName),
+		)
+	}
+	return normalized
+}
+
+// rejectUnknownFeature responds 404 if REQUIRE_KNOWN_FEATURE is set and the feature is not known to Unleash.
+// In strict environments, unknown features are an error rather than implicitly disabled, to catch typos and retired flags.
+func rejectUnknownFeature(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, featureName, appName string, known bool) bool {
+	if !env.RequireKnownFeature || known {
+		return false
+	}
+
+	span.SetStatus(codes.Error, "unknown feature")
+	span.SetAttributes(attribute.String("error.type", "unknown_feature"))
+	log.Warn("Unknown feature "+featureName+" requested by "+appName,
+		"error_type", "unknown_feature",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"feature", featureName,
+		"app_name", appName,
+	)
+	metrics.RecordFeatureError("unknown_feature")
+	writeError(w, http.StatusNotFound, "unknown_feature", "Unknown feature: "+featureName)
+	return true
+}
+
+// checkLogLevel returns the level to log a completed check at.
+// Slow checks are logged at Warn, so they can be diagnosed even when the trace is sampled out.
+func checkLogLevel(duration time.Duration) slog.Level {
+	if duration >= slowThreshold {
+		return slog.LevelWarn
+	}
+	return slog.LevelDebug
+}
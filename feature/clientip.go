@@ -0,0 +1,31 @@
+package feature
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/trust"
+)
+
+// remoteAddress returns the client address used as the Unleash context RemoteAddress.
+// If CLIENT_IP_HEADER is configured, the request comes from a trusted proxy and the header holds a valid IP,
+// that IP is used. Any other caller could spoof the address IP-based strategies see.
+// For list-valued headers like X-Forwarded-For, the first (original client) entry is used.
+// Otherwise it falls back to the IP of the connection's remote address, without the port.
+func remoteAddress(r *http.Request) string {
+	if env.ClientIPHeader != "" && trust.FromTrustedProxy(r) {
+		first, _, _ := strings.Cut(r.Header.Get(env.ClientIPHeader), ",")
+		if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+			return addr.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,209 @@
+package feature
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// ContextOverride is an Unleash context supplied verbatim by the caller.
+// Fields that are set override the values the proxy derives from the request;
+// omitted fields keep the derived values. Properties are merged into the derived properties.
+type ContextOverride struct {
+	UserId        string `json:"userId"`
+	SessionId     string `json:"sessionId"`
+	RemoteAddress string `json:"remoteAddress"`
+	Environment   string `json:"environment"`
+	AppName       string `json:"appName"`
+	CurrentTime   string `json:"currentTime"`
+	// Properties may have string, number or boolean values, see propertyString.
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// Validate checks that the fields of the override are well-formed.
+// Its properties are subject to the same limits as the request's properties, so they cannot replace podName.
+func (o *ContextOverride) Validate() error {
+	if err := validateCurrentTime(o.CurrentTime); err != nil {
+		return err
+	}
+	if o.RemoteAddress != "" {
+		if _, err := netip.ParseAddr(o.RemoteAddress); err != nil {
+			return fmt.Errorf("remoteAddress must be an IP address: %w", err)
+		}
+	}
+	return validateProperties(o.Properties)
+}
+
+// maxProperties is the maximum number of properties a request may pass through to Unleash.
+var maxProperties = 20
+
+// reservedProperties are property names callers may not set in the request's properties:
+// podName is set by the proxy, and the others are Unleash context fields that strategies look up by the same name.
+var reservedProperties = []string{"podName", "userId", "sessionId", "remoteAddress", "environment", "appName", "currentTime"}
+
+// validateProperties checks the number, names and values of the properties passed through in a request.
+func validateProperties(properties map[string]json.RawMessage) error {
+	if len(properties) > maxProperties {
+		return fmt.Errorf("at most %d properties are allowed, got %d", maxProperties, len(properties))
+	}
+	for key, value := range properties {
+		if key == "" {
+			return errors.New("property names must not be empty")
+		}
+		if slices.Contains(reservedProperties, key) {
+			return fmt.Errorf("property %q is reserved", key)
+		}
+		if _, err := propertyString(value); err != nil {
+			return fmt.Errorf("property %q %w", key, err)
+		}
+	}
+	return nil
+}
+
+// maxSeedLength is the maximum length of a request's seed.
+const maxSeedLength = 100
+
+// validateSeed checks that a seed, if set, is usable as a stickiness key.
+func validateSeed(seed string) error {
+	if seed == "" {
+		return nil
+	}
+	if strings.TrimSpace(seed) != seed {
+		return errors.New("seed must not have leading or trailing whitespace")
+	}
+	if len(seed) > maxSeedLength {
+		return fmt.Errorf("seed must be at most %d characters", maxSeedLength)
+	}
+	return nil
+}
+
+// validateCurrentTime checks that a currentTime, if set, is an RFC 3339 timestamp.
+func validateCurrentTime(currentTime string) error {
+	if currentTime == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, currentTime); err != nil {
+		return fmt.Errorf("currentTime must be an RFC 3339 timestamp: %w", err)
+	}
+	return nil
+}
+
+// propertyString coerces a JSON property value to the string Unleash expects.
+// Strings are used as is, and numbers and booleans as their JSON text, e.g. 42 -> "42" and true -> "true".
+// Objects, arrays and null are rejected, rather than silently dropped or stringified.
+func propertyString(value json.RawMessage) (string, error) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return "", errors.New("must have a value")
+	}
+
+	switch value[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	case '{', '[':
+		return "", errors.New("must be a string, number or boolean, not an object or array")
+	case 'n':
+		return "", errors.New("must be a string, number or boolean, not null")
+	default:
+		// The decoder has already validated the value, so this is a number or a boolean
+		return string(value), nil
+	}
+}
+
+// defaultProperties are added to the properties of every Unleash context, e.g. {"platform":"nais"}.
+// Properties derived from or supplied in the request take precedence.
+// DEFAULT_PROPERTIES is checked by env.Validate at startup.
+var defaultProperties, _ = parseDefaultProperties(env.DefaultProperties)
+
+// parseDefaultProperties parses DEFAULT_PROPERTIES, a JSON object of property names to values.
+func parseDefaultProperties(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var properties map[string]string
+	if err := json.Unmarshal([]byte(value), &properties); err != nil {
+		return nil, fmt.Errorf("failed to parse DEFAULT_PROPERTIES: %w", err)
+	}
+
+	return properties, nil
+}
+
+// newUnleashContext builds the Unleash context for a feature request.
+// Properties are merged in increasing precedence: default properties, request properties, podName,
+// unleashContext properties.
+// CurrentTime is defaulted to now unless the request sets it. A seed is used as both UserId and SessionId;
+// otherwise the SessionId is the anonymous session, if enabled, which may set a cookie on w.
+func newUnleashContext(w http.ResponseWriter, r *http.Request, req Request) unleashcontext.Context {
+	properties := make(map[string]string, len(defaultProperties)+len(req.Properties)+1)
+	maps.Copy(properties, defaultProperties)
+	for key, value := range req.Properties {
+		// Values are checked by validateProperties before the context is built
+		properties[key], _ = propertyString(value)
+	}
+	properties["podName"] = req.PodName
+
+	unleashCtx := unleashcontext.Context{
+		Environment:   clients.Environment(req.AppName),
+		UserId:        req.NavIdent,
+		AppName:       req.AppName,
+		RemoteAddress: remoteAddress(r),
+		CurrentTime:   req.CurrentTime,
+		Properties:    properties,
+	}
+
+	// A seed replaces both stickiness keys, so the result is the same for the same seed whatever else the request contains
+	if req.Seed != "" {
+		unleashCtx.UserId = req.Seed
+		unleashCtx.SessionId = req.Seed
+	} else {
+		unleashCtx.SessionId = anonymousSessionID(w, r)
+	}
+
+	if req.UnleashContext != nil {
+		applyOverride(&unleashCtx, req.UnleashContext)
+	}
+
+	return unleashCtx
+}
+
+// applyOverride applies the set fields of a caller-supplied context onto the derived context.
+func applyOverride(unleashCtx *unleashcontext.Context, override *ContextOverride) {
+	if override.UserId != "" {
+		unleashCtx.UserId = override.UserId
+	}
+	if override.SessionId != "" {
+		unleashCtx.SessionId = override.SessionId
+	}
+	if override.RemoteAddress != "" {
+		unleashCtx.RemoteAddress = override.RemoteAddress
+	}
+	if override.Environment != "" {
+		unleashCtx.Environment = override.Environment
+	}
+	if override.AppName != "" {
+		unleashCtx.AppName = override.AppName
+	}
+	if override.CurrentTime != "" {
+		unleashCtx.CurrentTime = override.CurrentTime
+	}
+	for key, value := range override.Properties {
+		// Values are checked by Validate before the context is built
+		unleashCtx.Properties[key], _ = propertyString(value)
+	}
+}
@@ -0,0 +1,181 @@
+package feature
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+)
+
+const (
+	// decisionBufferSize is the number of decisions buffered for the sink. Decisions are dropped when it is full,
+	// so a slow or unreachable sink never blocks feature checks.
+	decisionBufferSize = 10000
+
+	// decisionBatchSize is the maximum number of decisions posted to the sink at once.
+	decisionBatchSize = 500
+
+	// decisionFlushInterval is how often buffered decisions are posted, even if the batch is not full.
+	decisionFlushInterval = 5 * time.Second
+
+	// decisionPostTimeout bounds a single post to the sink.
+	decisionPostTimeout = 10 * time.Second
+)
+
+// Decision is an evaluation record exported to DECISION_SINK_URL for flag usage analytics.
+type Decision struct {
+	AppName string `json:"appName"`
+	Feature string `json:"feature"`
+	Enabled bool   `json:"enabled"`
+	// User is the HMAC-SHA256 of the evaluated userId, normally the navIdent, keyed with DECISION_USER_KEY,
+	// so decisions can be grouped by user without exporting the identity. A plain hash would not do, since every possible navIdent can be hashed.
+	// Empty for evaluations without a user, and for all evaluations unless DECISION_USER_KEY is set.
+	User      string    `json:"user,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+var (
+	// decisions buffers decisions for the sink. It is nil unless the sink is started.
+	decisions chan Decision
+	// stopDecisions signals the sink to post the remaining decisions and stop.
+	stopDecisions chan struct{}
+	// decisionsDone is closed when the sink has stopped.
+	decisionsDone chan struct{}
+)
+
+// StartDecisionSink starts posting evaluation decisions to DECISION_SINK_URL in the background, if it is set.
+// Call StopDecisionSink on shutdown to post the remaining decisions.
+func StartDecisionSink() {
+	if env.DecisionSinkURL == "" {
+		return
+	}
+
+	decisions = make(chan Decision, decisionBufferSize)
+	stopDecisions = make(chan struct{})
+	decisionsDone = make(chan struct{})
+
+	slog.Info("Exporting evaluation decisions",
+		slog.String("url", env.DecisionSinkURL),
+	)
+
+	go runDecisionSink(&http.Client{Timeout: decisionPostTimeout})
+}
+
+// StopDecisionSink posts the buffered decisions and stops the sink, waiting until ctx is done at most.
+func StopDecisionSink(ctx context.Context) {
+	if decisions == nil {
+		return
+	}
+
+	close(stopDecisions)
+
+	select {
+	case <-decisionsDone:
+	case <-ctx.Done():
+		slog.Warn("Decision sink did not finish in time, dropping the remaining decisions")
+	}
+}
+
+// recordDecision queues an evaluation decision for the sink, dropping it if the buffer is full.
+// userId is the user of the evaluated Unleash context.
+func recordDecision(appName, feature string, enabled bool, userId string) {
+	if decisions == nil {
+		return
+	}
+
+	decision := Decision{
+		AppName:   appName,
+		Feature:   feature,
+		Enabled:   enabled,
+		Timestamp: time.Now(),
+	}
+	if userId != "" && env.DecisionUserKey != "" {
+		mac := hmac.New(sha256.New, []byte(env.DecisionUserKey))
+		mac.Write([]byte(userId))
+		decision.User = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	select {
+	case decisions <- decision:
+	default:
+		metrics.RecordDecisionsDropped("buffer_full", 1)
+	}
+}
+
+// runDecisionSink collects decisions into batches and posts them, until stopped.
+func runDecisionSink(client *http.Client) {
+	defer close(decisionsDone)
+
+	ticker := time.NewTicker(decisionFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Decision, 0, decisionBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := postDecisions(client, batch); err != nil {
+			slog.Warn("Failed to post decisions to the sink",
+				slog.String("error", err.Error()),
+				slog.Int("count", len(batch)),
+			)
+			metrics.RecordDecisionsDropped("post_failed", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case decision := <-decisions:
+			batch = append(batch, decision)
+			if len(batch) >= decisionBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-stopDecisions:
+			// Drain what is buffered, without waiting for decisions still being made
+			for {
+				select {
+				case decision := <-decisions:
+					batch = append(batch, decision)
+					if len(batch) >= decisionBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// postDecisions posts a batch of decisions to the sink as a JSON array.
+func postDecisions(client *http.Client, batch []Decision) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(env.DecisionSinkURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sink responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
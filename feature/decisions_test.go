@@ -0,0 +1,203 @@
+package feature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// decisionSink is a fake decision sink that keeps the batches posted to it.
+type decisionSink struct {
+	mu      sync.Mutex
+	batches [][]Decision
+	status  int
+}
+
+func (s *decisionSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var batch []Decision
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	w.WriteHeader(s.status)
+}
+
+func (s *decisionSink) posted() [][]Decision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+// withDecisionSink starts exporting decisions to a fake sink responding with the given status, keyed with userKey.
+// The returned stop posts the remaining decisions; it is also called when the test is done.
+func withDecisionSink(t *testing.T, status int, userKey string) (sink *decisionSink, stop func()) {
+	t.Helper()
+	sink = &decisionSink{status: status}
+	server := httptest.NewServer(sink)
+	t.Cleanup(server.Close)
+
+	previousURL, previousKey := env.DecisionSinkURL, env.DecisionUserKey
+	env.DecisionSinkURL, env.DecisionUserKey = server.URL, userKey
+	StartDecisionSink()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			StopDecisionSink(ctx)
+			decisions = nil
+		})
+	}
+	t.Cleanup(func() {
+		stop()
+		env.DecisionSinkURL, env.DecisionUserKey = previousURL, previousKey
+	})
+
+	return sink, stop
+}
+
+func TestDecisionSinkPostsPseudonymisedDecisions(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+	sink, stop := withDecisionSink(t, http.StatusAccepted, "decision-key")
+
+	for _, body := range []string{
+		`{"appName":"` + testApp + `","navIdent":"Z123456"}`,
+		`{"appName":"` + testApp + `"}`,
+	} {
+		if w := checkFeature(PathPrefix+"new-ui", strings.NewReader(body)); w.Code != http.StatusOK {
+			t.Fatalf("status %d, body %s", w.Code, w.Body)
+		}
+	}
+	stop()
+
+	batches := sink.posted()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("posted batches %v, want one batch of 2 decisions", batches)
+	}
+
+	mac := hmac.New(sha256.New, []byte("decision-key"))
+	mac.Write([]byte("Z123456"))
+	wantUser := hex.EncodeToString(mac.Sum(nil))
+
+	withUser, withoutUser := batches[0][0], batches[0][1]
+	if withUser.AppName != testApp || withUser.Feature != "new-ui" || !withUser.Enabled || withUser.Timestamp.IsZero() {
+		t.Errorf("decision %+v, want an enabled new-ui decision for %s", withUser, testApp)
+	}
+	if withUser.User != wantUser {
+		t.Errorf("user %q, want the HMAC of the navIdent %q", withUser.User, wantUser)
+	}
+	if strings.Contains(withUser.User, "Z123456") {
+		t.Errorf("user %q exports the navIdent", withUser.User)
+	}
+	if withoutUser.User != "" {
+		t.Errorf("user %q for a check without navIdent, want none", withoutUser.User)
+	}
+}
+
+func TestDecisionSinkUsesTheEvaluatedUser(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+	sink, stop := withDecisionSink(t, http.StatusAccepted, "decision-key")
+
+	for _, body := range []string{
+		`{"appName":"` + testApp + `","navIdent":"Z123456","seed":"seed-1"}`,
+		`{"appName":"` + testApp + `","navIdent":"Z123456","unleashContext":{"userId":"Z654321"}}`,
+	} {
+		if w := checkFeature(PathPrefix+"new-ui", strings.NewReader(body)); w.Code != http.StatusOK {
+			t.Fatalf("status %d, body %s", w.Code, w.Body)
+		}
+	}
+	stop()
+
+	batches := sink.posted()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("posted batches %v, want one batch of 2 decisions", batches)
+	}
+
+	for i, userId := range []string{"seed-1", "Z654321"} {
+		mac := hmac.New(sha256.New, []byte("decision-key"))
+		mac.Write([]byte(userId))
+		if got, want := batches[0][i].User, hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("decision %d: user %q, want the HMAC of the evaluated user %q", i, got, userId)
+		}
+	}
+}
+
+func TestDecisionSinkOmitsUsersWithoutKey(t *testing.T) {
+	sink, stop := withDecisionSink(t, http.StatusAccepted, "")
+
+	recordDecision(testApp, "new-ui", true, "Z123456")
+	stop()
+
+	batches := sink.posted()
+	if len(batches) != 1 || len(batches[0]) != 1 {
+		t.Fatalf("posted batches %v, want one decision", batches)
+	}
+	if user := batches[0][0].User; user != "" {
+		t.Errorf("user %q without DECISION_USER_KEY, want none", user)
+	}
+}
+
+func TestDecisionSinkBatches(t *testing.T) {
+	sink, stop := withDecisionSink(t, http.StatusAccepted, "")
+
+	for range decisionBatchSize + 1 {
+		recordDecision(testApp, "new-ui", true, "")
+	}
+	stop()
+
+	batches := sink.posted()
+	if len(batches) != 2 || len(batches[0]) != decisionBatchSize || len(batches[1]) != 1 {
+		sizes := make([]int, len(batches))
+		for i, batch := range batches {
+			sizes[i] = len(batch)
+		}
+		t.Errorf("posted batch sizes %v, want [%d 1]", sizes, decisionBatchSize)
+	}
+}
+
+func TestDecisionSinkCountsFailedPosts(t *testing.T) {
+	dropped := metrics.FeatureDecisionsDropped.WithLabelValues("post_failed")
+	before := testutil.ToFloat64(dropped)
+	_, stop := withDecisionSink(t, http.StatusInternalServerError, "")
+
+	for range 3 {
+		recordDecision(testApp, "new-ui", true, "")
+	}
+	stop()
+
+	if got := testutil.ToFloat64(dropped) - before; got != 3 {
+		t.Errorf("feature_decisions_dropped_total{reason=post_failed} increased by %v, want 3", got)
+	}
+}
+
+func TestRecordDecisionDropsWhenBufferIsFull(t *testing.T) {
+	previous := decisions
+	decisions = make(chan Decision, 1)
+	t.Cleanup(func() { decisions = previous })
+
+	dropped := metrics.FeatureDecisionsDropped.WithLabelValues("buffer_full")
+	before := testutil.ToFloat64(dropped)
+
+	recordDecision(testApp, "new-ui", true, "")
+	recordDecision(testApp, "new-ui", false, "")
+
+	if got := testutil.ToFloat64(dropped) - before; got != 1 {
+		t.Errorf("feature_decisions_dropped_total{reason=buffer_full} increased by %v, want 1", got)
+	}
+}
@@ -0,0 +1,52 @@
+package feature
+
+import "sync"
+
+// maxTrackedResults bounds the number of (app, feature, user) results kept for flip detection.
+// When the bound is reached the tracked results are reset, trading a few missed flips for fixed memory.
+const maxTrackedResults = 10000
+
+type flipKey struct {
+	appName string
+	feature string
+	userId  string
+}
+
+var (
+	lastResults   = make(map[flipKey]bool)
+	lastResultsMu sync.Mutex
+)
+
+// trackFlip records the evaluated result for the user and reports whether
+// it differs from the previous result for the same app, feature and user.
+// Evaluations without a user are not tracked, since they have no stable identity to compare against.
+func trackFlip(appName, feature, userId string, enabled bool) bool {
+	if userId == "" {
+		return false
+	}
+
+	key := flipKey{appName: appName, feature: feature, userId: userId}
+
+	lastResultsMu.Lock()
+	defer lastResultsMu.Unlock()
+
+	previous, seen := lastResults[key]
+	if !seen && len(lastResults) >= maxTrackedResults {
+		clear(lastResults)
+	}
+	lastResults[key] = enabled
+
+	return seen && previous != enabled
+}
+
+// forgetFlips drops the tracked results of the app.
+func forgetFlips(appName string) {
+	lastResultsMu.Lock()
+	defer lastResultsMu.Unlock()
+
+	for key := range lastResults {
+		if key.appName == appName {
+			delete(lastResults, key)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package feature
+
+// ForgetApps drops the cached and last-known results, tracked flips and kill switch state of apps whose clients were removed,
+// e.g. by a reload, so removed apps hold no memory and an app that is added again starts afresh.
+func ForgetApps(appNames []string) {
+	for _, appName := range appNames {
+		responseCache.removeApp(appName)
+		lastKnownResults.removeApp(appName)
+		forgetFlips(appName)
+		killSwitches.Delete(appName)
+	}
+}
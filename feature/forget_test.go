@@ -0,0 +1,39 @@
+package feature
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForgetApps(t *testing.T) {
+	withKillSwitch(t, time.Minute)
+	evaluator := withEvaluator(t, map[string]bool{testKillSwitch: true})
+	t.Cleanup(func() { ForgetApps([]string{testApp, "other-app"}) })
+
+	for _, app := range []string{testApp, "other-app"} {
+		responseCache.put(cacheEntry{key: cacheKey{appName: app, feature: "new-ui"}, expires: time.Now().Add(time.Minute)})
+		trackFlip(app, "new-ui", "Z123456", true)
+		killSwitchActive(app, evaluator)
+	}
+
+	ForgetApps([]string{testApp})
+
+	if _, ok := responseCache.get(cacheKey{appName: testApp, feature: "new-ui"}, time.Now()); ok {
+		t.Error("cached result of the forgotten app kept")
+	}
+	if _, ok := responseCache.get(cacheKey{appName: "other-app", feature: "new-ui"}, time.Now()); !ok {
+		t.Error("cached result of another app dropped")
+	}
+	if trackFlip(testApp, "new-ui", "Z123456", false) {
+		t.Error("tracked result of the forgotten app kept")
+	}
+	if !trackFlip("other-app", "new-ui", "Z123456", false) {
+		t.Error("tracked result of another app dropped")
+	}
+	if _, ok := killSwitches.Load(testApp); ok {
+		t.Error("kill switch state of the forgotten app kept")
+	}
+	if _, ok := killSwitches.Load("other-app"); !ok {
+		t.Error("kill switch state of another app dropped")
+	}
+}
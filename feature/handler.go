@@ -1,20 +1,25 @@
 package feature
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/Unleash/unleash-go-sdk/v5"
 	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
 	"github.com/navikt/klage-unleash-proxy/clients"
 	"github.com/navikt/klage-unleash-proxy/env"
-	"github.com/navikt/klage-unleash-proxy/logging"
 	"github.com/navikt/klage-unleash-proxy/metrics"
-	"github.com/navikt/klage-unleash-proxy/nais"
+	"github.com/navikt/klage-unleash-proxy/telemetry"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -27,10 +32,55 @@ var tracer trace.Tracer
 
 var serverHeader = env.NaisAppName + "/" + env.AppVersion
 
+// inFlight is the number of feature requests currently being handled.
+var inFlight atomic.Int64
+
+// InFlight returns the number of feature requests currently being handled.
+func InFlight() int64 {
+	return inFlight.Load()
+}
+
+// requestTimeout bounds how long a feature check may take. Zero disables the timeout.
+var requestTimeout = 2 * time.Second
+
+// maxBodyBytes is the maximum size of a feature check request body.
+var maxBodyBytes int64 = 64 * 1024
+
+// slowThreshold is the duration from which a feature check is logged as slow.
+var slowThreshold = 100 * time.Millisecond
+
+// unleashSpanRatio is the fraction of successful evaluations that get an unleash.IsEnabled child span.
+// Error paths are always recorded on the handler span.
+var unleashSpanRatio = 1.0
+
 // InitTracer initializes the tracer after OpenTelemetry setup.
 // Call this after telemetry.Initialize() to ensure proper tracing.
 func InitTracer() {
 	tracer = otel.Tracer(env.NaisAppName)
+	unleashSpanRatio = env.Ratio("UNLEASH_SPAN_SAMPLE_RATIO", env.UnleashSpanSampleRatio, 1)
+}
+
+// Configure reads the feature settings from the environment, falling back to the defaults for invalid values.
+// Call this at startup after logging.Initialize, so the warnings about invalid values are logged as JSON.
+func Configure() {
+	requestTimeout = env.Duration("FEATURE_REQUEST_TIMEOUT", env.FeatureRequestTimeout, requestTimeout)
+	maxBodyBytes = int64(env.Int("MAX_REQUEST_BODY_BYTES", env.MaxRequestBodyBytes, int(maxBodyBytes)))
+	slowThreshold = env.Duration("SLOW_FEATURE_REQUEST_THRESHOLD", env.SlowFeatureRequestThreshold, slowThreshold)
+	maxBatchSize = env.Int("MAX_BATCH_SIZE", env.MaxBatchSize, maxBatchSize)
+	maxProperties = env.Int("MAX_REQUEST_PROPERTIES", env.MaxRequestProperties, maxProperties)
+	cacheTTL = env.Duration("FEATURE_CACHE_TTL", env.FeatureCacheTTL, cacheTTL)
+	staleResultsAfter = env.Duration("STALE_RESULTS_AFTER", env.StaleResultsAfter, staleResultsAfter)
+	evaluationRetryDelay = env.Duration("EVALUATION_RETRY_DELAY", env.EvaluationRetryDelay, evaluationRetryDelay)
+	killSwitchTTL = env.Duration("KILL_SWITCH_TTL", env.KillSwitchTTL, killSwitchTTL)
+	statsWindow = env.Duration("STATS_WINDOW", env.StatsWindow, statsWindow)
+	statsMaxFeatures = env.Int("STATS_MAX_FEATURES", env.StatsMaxFeatures, statsMaxFeatures)
+	shadowSlots = make(chan struct{}, max(env.Int("SHADOW_CONCURRENCY", env.ShadowConcurrency, cap(shadowSlots)), 1))
+}
+
+// sampleUnleashSpan reports whether this evaluation should get an unleash.IsEnabled child span.
+// Force-sampled requests always get one.
+func sampleUnleashSpan(ctx context.Context) bool {
+	return unleashSpanRatio >= 1 || telemetry.ForceSampled(ctx) || (unleashSpanRatio > 0 && rand.Float64() < unleashSpanRatio)
 }
 
 // Request represents the JSON body for feature check requests.
@@ -38,11 +88,85 @@ type Request struct {
 	NavIdent string `json:"navIdent"`
 	AppName  string `json:"appName"`
 	PodName  string `json:"podName"`
+	// CurrentTime optionally sets the evaluation time as an RFC 3339 timestamp, for testing time-based strategies.
+	CurrentTime string `json:"currentTime,omitempty"`
+	// Seed optionally replaces the user and session as the stickiness key of gradual rollouts,
+	// for reproducible results in tests.
+	Seed string `json:"seed,omitempty"`
+	// Properties are passed through to the Unleash context, e.g. for custom strategies keyed on unitId.
+	// They may have string, number or boolean values, see propertyString.
+	Properties map[string]json.RawMessage `json:"properties,omitempty"`
+	// UnleashContext optionally overrides the Unleash context derived from the fields above.
+	UnleashContext *ContextOverride `json:"unleashContext,omitempty"`
+}
+
+// UnmarshalJSON decodes a Request, accepting snake_case keys (nav_ident, app_name, pod_name, current_time)
+// in addition to the canonical camelCase keys. The camelCase key wins if both are present.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type request Request
+	var aux struct {
+		request
+		SnakeNavIdent    string `json:"nav_ident"`
+		SnakeAppName     string `json:"app_name"`
+		SnakePodName     string `json:"pod_name"`
+		SnakeCurrentTime string `json:"current_time"`
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*r = Request(aux.request)
+	if r.NavIdent == "" {
+		r.NavIdent = aux.SnakeNavIdent
+	}
+	if r.AppName == "" {
+		r.AppName = aux.SnakeAppName
+	}
+	if r.PodName == "" {
+		r.PodName = aux.SnakePodName
+	}
+	if r.CurrentTime == "" {
+		r.CurrentTime = aux.SnakeCurrentTime
+	}
+
+	return nil
+}
+
+// countingReader counts the bytes read from the wrapped reader.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // Response represents the JSON response for feature check requests.
 type Response struct {
 	Enabled bool `json:"enabled"`
+	// Known is whether the feature exists in Unleash. Only included when requested with ?meta=true.
+	Known *bool `json:"known,omitempty"`
+	// Stale is whether the result is the last-known result of a client whose feature data is stale,
+	// see STALE_RESULTS_AFTER. Only included when requested with ?meta=true, and only when true.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// ErrorResponse represents the JSON response for failed feature check requests.
+// Code is the same error type as the error.type span attribute and the error_type metric label.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeError writes a JSON error response with the given status code.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
 }
 
 // IsValidName validates the feature name according to Unleash rules:
@@ -62,36 +186,15 @@ func IsValidName(name string) bool {
 }
 
 // Handler handles feature check requests.
-// It expects requests to POST or QUERY /features/{featureName} with a JSON body.
+// It expects requests to POST or QUERY /features/{featureName} with a JSON body,
+// or GET /features/{featureName} with the same fields as query parameters.
 func Handler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	// Add version headers to all responses
-	w.Header().Set("Server", serverHeader)
-	w.Header().Set("App-Version", env.AppVersion)
+	ctx, span, log, end := startCheck(w, r, "featureHandler")
+	defer end()
 
-	ctx := r.Context()
-
-	// Start a span for the feature check
-	ctx, span := tracer.Start(ctx, "featureHandler",
-		trace.WithAttributes(
-			attribute.String("http.method", r.Method),
-			attribute.String("http.path", r.URL.Path),
-		),
-	)
-	defer span.End()
-
-	log := logging.FromContext(ctx)
-
-	if r.Method != http.MethodPost && r.Method != "QUERY" {
-		span.SetStatus(codes.Error, "method not allowed")
-		span.SetAttributes(attribute.String("error.type", "method_not_allowed"))
-		log.Warn("Method not allowed",
-			"method", r.Method,
-			"path", r.URL.Path,
-		)
-		metrics.RecordFeatureError("method_not_allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, span, log, allowedMethods) {
 		return
 	}
 
@@ -101,46 +204,295 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		span.SetStatus(codes.Error, "missing feature name")
 		span.SetAttributes(attribute.String("error.type", "missing_feature"))
 		log.Warn("Missing feature name",
+			"error_type", "missing_feature",
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
 		metrics.RecordFeatureError("missing_feature_name")
-		http.Error(w, "Feature name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "missing_feature", "Feature name is required")
+		return
+	}
+
+	featureName, ok := checkFeatureName(w, r, span, log, featureName)
+	if !ok {
+		return
+	}
+
+	req, client, ok := decodeRequest(w, r, span, log, featureName)
+	if !ok {
 		return
 	}
 
-	span.SetAttributes(attribute.String("feature.name", featureName))
+	// Deprecated features are still evaluated, but callers are warned so they migrate away
+	if slices.Contains(env.DeprecatedFeatures, featureName) {
+		w.Header().Set("Deprecation", "true")
+		span.SetAttributes(attribute.Bool("feature.deprecated", true))
+		log.Warn(fmt.Sprintf("Deprecated feature %s requested by %s", featureName, req.AppName),
+			"feature", featureName,
+			"app_name", req.AppName,
+			"pod_name", req.PodName,
+		)
+	}
+
+	// The kill switch takes precedence over all evaluation, including hooks
+	if killSwitchActive(req.AppName, client) {
+		span.SetAttributes(
+			attribute.Bool("proxy.kill_switch", true),
+			attribute.Bool("feature.enabled", killSwitchDefault),
+		)
+		log.Debug(fmt.Sprintf("Kill switch active, returning %t for %s - %s", killSwitchDefault, req.AppName, featureName),
+			"feature", featureName,
+			"enabled", killSwitchDefault,
+			"app_name", req.AppName,
+		)
+		metrics.RecordFeatureRequest(killSwitchFeatureLabel, req.AppName, killSwitchDefault, time.Since(startTime))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Enabled: killSwitchDefault})
+		return
+	}
+
+	unleashCtx := newUnleashContext(w, r, req)
+
+	// Evaluate in the background, so the request can give up on an evaluation that outlives its deadline
+	type evaluationResult struct{ enabled, known, cacheHit, stale bool }
+	results := make(chan evaluationResult, 1)
+	go func() {
+		enabled, known, cacheHit, stale := evaluateFeature(ctx, client, featureName, unleashCtx, req)
+		results <- evaluationResult{enabled, known, cacheHit, stale}
+	}()
+
+	var enabled, known, stale bool
+	select {
+	case res := <-results:
+		enabled, known, stale = res.enabled, res.known, res.stale
+		if res.cacheHit {
+			span.SetAttributes(attribute.Bool("feature.cache_hit", true))
+			metrics.RecordFeatureCacheHit(featureLabel(featureName, res.known))
+		}
+		if res.stale {
+			span.SetAttributes(attribute.Bool("feature.stale", true))
+		}
+	case <-ctx.Done():
+		abandonEvaluation(ctx, w, r, span, log.With("feature", featureName), req.AppName)
+		return
+	}
+
+	if rejectUnknownFeature(w, r, span, log, featureName, req.AppName, known) {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Bool("feature.enabled", enabled),
+		attribute.Bool("feature.known", known),
+	)
+
+	// Record Prometheus metrics
+	duration := time.Since(startTime)
+	metrics.RecordFeatureRequest(featureLabel(featureName, known), req.AppName, enabled, duration)
+
+	// Inversion applies after hooks, so metrics and stats keep the evaluated value and only the response is negated
+	result := enabled
+	inverted := shouldInvert(r, featureName)
+	if inverted {
+		result = !enabled
+		span.SetAttributes(attribute.Bool("feature.inverted", true))
+		metrics.RecordFeatureInversion(featureLabel(featureName, known))
+	}
+
+	log.Log(ctx, checkLogLevel(duration), fmt.Sprintf("Feature check for %s - %s = %t", req.AppName, featureName, enabled),
+		"feature", featureName,
+		"enabled", enabled,
+		"inverted", inverted,
+		"result", result,
+		"user_id", req.NavIdent,
+		"app_name", req.AppName,
+		"pod_name", req.PodName,
+		"duration", duration.Milliseconds(),
+	)
 
-	// Validate feature name according to Unleash rules
-	if !IsValidName(featureName) {
-		span.SetStatus(codes.Error, "invalid feature name")
-		span.SetAttributes(attribute.String("error.type", "invalid_feature"))
-		log.Warn("Invalid feature name",
+	if r.Method == "QUERY" {
+		setQueryHeaders(w, r, featureName, req)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := Response{Enabled: result}
+	// Metadata is opt-in, so existing callers keep getting the same response shape
+	if r.URL.Query().Get("meta") == "true" {
+		response.Known = &known
+		response.Stale = stale
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// abandonEvaluation ends a feature request whose context is done before its evaluation finished.
+// It answers 504 if the request timed out, and only records the error if the caller went away.
+func abandonEvaluation(ctx context.Context, w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, appName string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		span.SetStatus(codes.Error, "feature request timed out")
+		span.SetAttributes(attribute.String("error.type", "timeout"))
+		log.Warn("Feature request timed out",
+			"error_type", "timeout",
 			"method", r.Method,
 			"path", r.URL.Path,
-			"feature", featureName,
+			"app_name", appName,
+			"timeout", requestTimeout.String(),
 		)
-		metrics.RecordFeatureError("invalid_feature_name")
-		http.Error(w, "Invalid feature name: must be URL-friendly, 1-100 characters, and not '.' or '..'", http.StatusBadRequest)
+		metrics.RecordFeatureError("timeout")
+		writeError(w, http.StatusGatewayTimeout, "timeout", "feature request timed out")
 		return
 	}
 
-	// Parse JSON body
+	// The caller went away, so there is nobody to respond to
+	span.SetStatus(codes.Error, "request canceled")
+	span.SetAttributes(attribute.String("error.type", "canceled"))
+	log.Debug("Feature request canceled by the caller",
+		"error_type", "canceled",
+		"app_name", appName,
+	)
+	metrics.RecordFeatureError("canceled")
+}
+
+// evaluateFeature evaluates a feature for the request, from the response cache if enabled, and applies flip tracking,
+// hooks, stats and decision export.
+// It returns the final result after hooks, whether the feature is known to the SDK, whether the evaluation
+// came from the cache, and whether it is the last-known result of a stale client.
+// Once ctx is done, the request has been answered without this result, so the side effects are skipped.
+func evaluateFeature(ctx context.Context, client clients.Evaluator, featureName string, unleashCtx unleashcontext.Context, req Request) (enabled bool, known bool, cacheHit bool, stale bool) {
+	enabled, known, cacheHit, stale = evaluateLastKnown(ctx, client, featureName, unleashCtx, req)
+	if ctx.Err() != nil {
+		return enabled, known, cacheHit, stale
+	}
+
+	// Flips and decisions are keyed on the user the feature was evaluated for, which a seed or unleashContext
+	// can set to someone other than the navIdent
+	if trackFlip(req.AppName, featureName, unleashCtx.UserId, enabled) {
+		metrics.RecordFeatureFlip(featureLabel(featureName, known), req.AppName)
+	}
+
+	enabled = runHooks(ctx, Evaluation{
+		Feature: featureName,
+		Context: unleashCtx,
+		Enabled: enabled,
+		Known:   known,
+	})
+
+	// Hooks may be slow, so the request can have timed out in the meantime
+	if ctx.Err() != nil {
+		return enabled, known, cacheHit, stale
+	}
+
+	recordStats(featureName, enabled)
+	recordDecision(req.AppName, featureName, enabled, unleashCtx.UserId)
+
+	return enabled, known, cacheHit, stale
+}
+
+// evaluateTraced evaluates a feature in the SDK, with an unleash.IsEnabled child span for a sampled fraction
+// of requests, and starts the shadow evaluation.
+func evaluateTraced(ctx context.Context, client clients.Evaluator, featureName string, unleashCtx unleashcontext.Context, req Request) (enabled bool, known bool) {
+	var unleashSpan trace.Span
+	if sampleUnleashSpan(ctx) {
+		_, unleashSpan = tracer.Start(ctx, "unleash.IsEnabled",
+			trace.WithAttributes(
+				attribute.String("feature.name", featureName),
+				attribute.String("user_id", req.NavIdent),
+				attribute.String("app_name", req.AppName),
+				attribute.String("pod_name", req.PodName),
+			),
+		)
+	}
+	enabled, known = evaluate(client, featureName, unleashCtx)
+	if unleashSpan != nil {
+		unleashSpan.SetAttributes(attribute.Bool("feature.enabled", enabled))
+		unleashSpan.End()
+	}
+
+	shadowEvaluate(req.AppName, featureName, unleashCtx, enabled, known)
+
+	return enabled, known
+}
+
+// queryRequest reads the evaluation context of a GET feature request from its query parameters.
+func queryRequest(r *http.Request) Request {
+	query := r.URL.Query()
+	return Request{
+		NavIdent:    query.Get("navIdent"),
+		AppName:     query.Get("appName"),
+		PodName:     query.Get("podName"),
+		CurrentTime: query.Get("currentTime"),
+		Seed:        query.Get("seed"),
+	}
+}
+
+// decodeRequest decodes and validates a single feature request and looks up the caller's client.
+// GET requests are read from the query parameters, all other methods from the JSON body.
+// On failure it records the error and writes the response, and returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, featureName string) (Request, clients.Evaluator, bool) {
+	log = log.With("feature", featureName)
+
 	var req Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		span.SetStatus(codes.Error, "invalid JSON body")
-		span.RecordError(err)
-		log.Warn("Invalid JSON body",
+	if r.Method == http.MethodGet {
+		// Flag results can change at any time, so GET responses must not be served from a cache
+		w.Header().Set("Cache-Control", "no-store")
+		req = queryRequest(r)
+	} else if !decodeBody(w, r, span, log, &req) {
+		return Request{}, nil, false
+	}
+
+	client, ok := validateRequest(w, r, span, log, &req)
+	if !ok {
+		return Request{}, nil, false
+	}
+
+	return req, client, true
+}
+
+// decodeBody decodes the JSON body of a feature request into v, bounded by MAX_REQUEST_BODY_BYTES.
+// On failure it records the error and writes the response, and returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, v any) bool {
+	body := &countingReader{Reader: http.MaxBytesReader(w, r.Body, maxBodyBytes)}
+	err := json.NewDecoder(body).Decode(v)
+	metrics.RecordRequestBodySize(body.n)
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		span.SetStatus(codes.Error, "request body too large")
+		span.SetAttributes(attribute.String("error.type", "body_too_large"))
+		log.Warn("Request body too large",
+			"error_type", "body_too_large",
 			"method", r.Method,
 			"path", r.URL.Path,
-			"feature", featureName,
-			"error", err.Error(),
+			"limit", maxBytesErr.Limit,
 		)
-		metrics.RecordFeatureError("invalid_json_body")
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
+		metrics.RecordFeatureError("body_too_large")
+		writeError(w, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+		return false
 	}
 
+	span.SetStatus(codes.Error, "invalid JSON body")
+	span.RecordError(err)
+	span.SetAttributes(attribute.String("error.type", "invalid_json_body"))
+	log.Warn("Invalid JSON body",
+		"error_type", "invalid_json_body",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err.Error(),
+	)
+	metrics.RecordFeatureError("invalid_json_body")
+	writeError(w, http.StatusBadRequest, "invalid_json_body", "Invalid JSON body")
+	return false
+}
+
+// validateRequest validates a decoded feature request, looks up the caller's client and applies the default navIdent.
+// It is shared by the single and batch feature checks, so both accept and reject the same requests.
+// On failure it records the error and writes the response, and returns false.
+func validateRequest(w http.ResponseWriter, r *http.Request, span trace.Span, log *slog.Logger, req *Request) (clients.Evaluator, bool) {
 	span.SetAttributes(
 		attribute.String("request.app_name", req.AppName),
 		attribute.String("request.pod_name", req.PodName),
@@ -151,71 +503,91 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		span.SetStatus(codes.Error, "missing app_name")
 		span.SetAttributes(attribute.String("error.type", "missing_app_name"))
 		log.Warn("Missing app_name in request body",
+			"error_type", "missing_app_name",
 			"method", r.Method,
 			"path", r.URL.Path,
-			"feature", featureName,
 		)
 		metrics.RecordFeatureError("missing_app_name")
-		http.Error(w, fmt.Sprintf("app_name is required in request body, must be one of the allowed inbound applications: %s", strings.Join(nais.InboundApps, ", ")), http.StatusBadRequest)
-		return
+		writeError(w, http.StatusBadRequest, "missing_app_name", fmt.Sprintf("app_name is required in request body, must be one of the allowed inbound applications: %s", strings.Join(clients.InboundApps(), ", ")))
+		return nil, false
 	}
 
 	// Get the Unleash client for the specified app
 	client, ok := clients.Get(req.AppName)
+	if !ok && clients.IsValidApp(req.AppName) {
+		span.SetStatus(codes.Error, "app client not ready")
+		span.SetAttributes(attribute.String("error.type", "app_not_ready"))
+		log.Error("No ready Unleash client for app_name: "+req.AppName,
+			"error_type", "app_not_ready",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"app_name", req.AppName,
+		)
+		metrics.RecordFeatureError("app_not_ready")
+		writeError(w, http.StatusServiceUnavailable, "app_not_ready", "The Unleash client for app_name "+req.AppName+" is not ready")
+		return nil, false
+	}
+	if !ok {
+		client, ok = unregisteredClient(log, req.AppName)
+	}
 	if !ok {
 		span.SetStatus(codes.Error, "unknown app_name")
 		span.SetAttributes(attribute.String("error.type", "unknown_app_name"))
 		log.Warn("Unknown app_name: "+req.AppName,
+			"error_type", "unknown_app_name",
 			"method", r.Method,
 			"path", r.URL.Path,
-			"feature", featureName,
 			"app_name", req.AppName,
 		)
 		metrics.RecordFeatureError("unknown_app_name")
-		http.Error(w, fmt.Sprintf("Unknown app_name: must be one of the allowed inbound applications: %s", strings.Join(nais.InboundApps, ", ")), http.StatusBadRequest)
-		return
+		writeError(w, http.StatusBadRequest, "unknown_app_name", fmt.Sprintf("Unknown app_name: must be one of the allowed inbound applications: %s", strings.Join(clients.InboundApps(), ", ")))
+		return nil, false
 	}
 
-	// CurrentTime is defaulted to now.
-	unleashCtx := unleashcontext.Context{
-		Environment:   env.UnleashServerAPIEnv,
-		UserId:        req.NavIdent,
-		AppName:       req.AppName,
-		RemoteAddress: r.RemoteAddr,
-		Properties: map[string]string{
-			"podName": req.PodName,
-		},
-	}
-
-	// Create a child span for the Unleash check
-	_, unleashSpan := tracer.Start(ctx, "unleash.IsEnabled",
-		trace.WithAttributes(
-			attribute.String("feature.name", featureName),
-			attribute.String("user_id", req.NavIdent),
-			attribute.String("app_name", req.AppName),
-			attribute.String("pod_name", req.PodName),
-		),
-	)
-	enabled := client.IsEnabled(featureName, unleash.WithContext(unleashCtx))
-	unleashSpan.SetAttributes(attribute.Bool("feature.enabled", enabled))
-	unleashSpan.End()
+	// The remaining checks only differ in the field they validate
+	checks := []struct {
+		field     string
+		errorType string
+		err       func() error
+	}{
+		{"unleashContext", "invalid_unleash_context", func() error {
+			if req.UnleashContext == nil {
+				return nil
+			}
+			return req.UnleashContext.Validate()
+		}},
+		{"currentTime", "invalid_current_time", func() error { return validateCurrentTime(req.CurrentTime) }},
+		{"seed", "invalid_seed", func() error { return validateSeed(req.Seed) }},
+		{"properties", "invalid_properties", func() error { return validateProperties(req.Properties) }},
+	}
+	for _, check := range checks {
+		err := check.err()
+		if err == nil {
+			continue
+		}
 
-	span.SetAttributes(attribute.Bool("feature.enabled", enabled))
+		span.SetStatus(codes.Error, "invalid "+check.field)
+		span.SetAttributes(attribute.String("error.type", check.errorType))
+		span.RecordError(err)
+		log.Warn("Invalid "+check.field+" in request",
+			"error_type", check.errorType,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"app_name", req.AppName,
+			"error", err.Error(),
+		)
+		metrics.RecordFeatureError(check.errorType)
+		writeError(w, http.StatusBadRequest, check.errorType, "Invalid "+check.field+": "+err.Error())
+		return nil, false
+	}
 
-	// Record Prometheus metrics
-	duration := time.Since(startTime)
-	metrics.RecordFeatureRequest(featureName, req.AppName, enabled, duration)
+	for key, value := range req.Properties {
+		// Values are checked by validateProperties
+		property, _ := propertyString(value)
+		span.SetAttributes(attribute.String("request.property."+key, property))
+	}
 
-	log.Debug(fmt.Sprintf("Feature check for %s - %s = %t", req.AppName, featureName, enabled),
-		"feature", featureName,
-		"enabled", enabled,
-		"user_id", req.NavIdent,
-		"app_name", req.AppName,
-		"pod_name", req.PodName,
-		"duration", duration.Milliseconds(),
-	)
+	applyDefaultNavIdent(log, req)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(Response{Enabled: enabled})
+	return client, true
 }
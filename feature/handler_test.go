@@ -0,0 +1,1338 @@
+package feature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/navikt/klage-unleash-proxy/trust"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testApp = "kabal-frontend"
+
+func TestMain(m *testing.M) {
+	InitTracer()
+	os.Exit(m.Run())
+}
+
+// withEvaluator serves testApp from a fake Evaluator with the given features for the duration of the test.
+func withEvaluator(t testing.TB, features map[string]bool) *clientstest.Evaluator {
+	t.Helper()
+	evaluator := clientstest.NewEvaluator(features)
+	clients.RegisterEvaluator(testApp, evaluator)
+	t.Cleanup(func() { clients.UnregisterEvaluator(testApp) })
+	return evaluator
+}
+
+// checkFeature sends a feature check to Handler and returns the recorded response.
+func checkFeature(path string, body io.Reader) *httptest.ResponseRecorder {
+	return serveFeature(httptest.NewRequest(http.MethodPost, path, body))
+}
+
+// serveFeature sends the request to Handler and returns the recorded response.
+func serveFeature(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	Handler(w, r)
+	return w
+}
+
+// decodeResponse decodes a successful feature check response, failing the test for any other response.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return response
+}
+
+func TestHandlerRecordsFeatureRequests(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	requests := metrics.FeatureRequestsTotal.WithLabelValues("new-ui", testApp, "true")
+	before := testutil.ToFloat64(requests)
+
+	for range 3 {
+		w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","navIdent":"Z123456"}`))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d, body %s", w.Code, w.Body)
+		}
+		var response Response
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil || !response.Enabled {
+			t.Fatalf("response %+v, err %v, want enabled", response, err)
+		}
+	}
+
+	if got := testutil.ToFloat64(requests) - before; got != 3 {
+		t.Errorf("feature_requests_total increased by %v, want 3", got)
+	}
+}
+
+func TestHandlerRecordsFeatureErrors(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		errorType string
+	}{
+		{name: "missing feature", path: PathPrefix, body: `{"appName":"` + testApp + `"}`, errorType: "missing_feature_name"},
+		{name: "invalid feature", path: PathPrefix + "..", body: `{"appName":"` + testApp + `"}`, errorType: "invalid_feature_name"},
+		{name: "invalid JSON", path: PathPrefix + "new-ui", body: `{`, errorType: "invalid_json_body"},
+		{name: "missing app", path: PathPrefix + "new-ui", body: `{}`, errorType: "missing_app_name"},
+		{name: "unknown app", path: PathPrefix + "new-ui", body: `{"appName":"not-an-app"}`, errorType: "unknown_app_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := metrics.FeatureRequestErrors.WithLabelValues(tt.errorType)
+			before := testutil.ToFloat64(errors)
+
+			w := checkFeature(tt.path, strings.NewReader(tt.body))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+			}
+
+			if got := testutil.ToFloat64(errors) - before; got != 1 {
+				t.Errorf("feature_request_errors_total{error_type=%q} increased by %v, want 1", tt.errorType, got)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsOversizedBody(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	padding := strings.Repeat("x", int(maxBodyBytes))
+	w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","podName":"`+padding+`"}`))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "body_too_large" {
+		t.Errorf("code = %q, want body_too_large", response.Code)
+	}
+}
+
+func TestHandlerRejectsInvalidUnleashContextProperties(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	tooMany := make([]string, 0, maxProperties+1)
+	for i := range maxProperties + 1 {
+		tooMany = append(tooMany, fmt.Sprintf(`"key%d":"value"`, i))
+	}
+
+	tests := []struct {
+		name       string
+		properties string
+	}{
+		{name: "reserved podName", properties: `{"podName":"spoofed"}`},
+		{name: "reserved context field", properties: `{"userId":"Z999999"}`},
+		{name: "empty name", properties: `{"":"value"}`},
+		{name: "too many", properties: "{" + strings.Join(tooMany, ",") + "}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"appName":"` + testApp + `","podName":"pod-1","unleashContext":{"properties":` + tt.properties + `}}`
+			w := checkFeature(PathPrefix+"new-ui", strings.NewReader(body))
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var response ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if response.Code != "invalid_unleash_context" {
+				t.Errorf("code = %q, want invalid_unleash_context", response.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerIgnoresXDebugFromUntrustedClients(t *testing.T) {
+	withKillSwitch(t, time.Minute)
+	withEvaluator(t, map[string]bool{testKillSwitch: false, "new-ui": true})
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	// No proxies are trusted in tests, so the request's X-Debug must not enable debug logging
+	r := httptest.NewRequest(http.MethodPost, PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`))
+	r.Header.Set("X-Debug", "true")
+	w := httptest.NewRecorder()
+	Handler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	if strings.Contains(buf.String(), "Kill switch active") {
+		t.Errorf("logged at debug level for an untrusted X-Debug request: %s", buf.String())
+	}
+}
+
+func TestHandlerCountsFlips(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"flip-feature": true})
+	flips := metrics.FeatureFlipsTotal.WithLabelValues("flip-feature", testApp)
+	before := testutil.ToFloat64(flips)
+
+	steps := []struct {
+		navIdent  string
+		enabled   bool
+		wantFlips float64
+	}{
+		{navIdent: "Z100001", enabled: true, wantFlips: 0},
+		{navIdent: "Z100001", enabled: true, wantFlips: 0},
+		{navIdent: "Z100001", enabled: false, wantFlips: 1},
+		{navIdent: "Z100002", enabled: true, wantFlips: 1},
+		{navIdent: "Z100001", enabled: true, wantFlips: 2},
+		{navIdent: "", enabled: false, wantFlips: 2},
+		{navIdent: "", enabled: true, wantFlips: 2},
+	}
+
+	for i, step := range steps {
+		evaluator.Features = map[string]bool{"flip-feature": step.enabled}
+		body := `{"appName":"` + testApp + `","navIdent":"` + step.navIdent + `"}`
+		if got := decodeResponse(t, checkFeature(PathPrefix+"flip-feature", strings.NewReader(body))); got.Enabled != step.enabled {
+			t.Fatalf("step %d: enabled %v, want %v", i, got.Enabled, step.enabled)
+		}
+		if got := testutil.ToFloat64(flips) - before; got != step.wantFlips {
+			t.Errorf("step %d: feature_flips_total increased by %v, want %v", i, got, step.wantFlips)
+		}
+	}
+}
+
+func TestHandlerCountsFlipsForTheEvaluatedUser(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"flip-seeded": true})
+	flips := metrics.FeatureFlipsTotal.WithLabelValues("flip-seeded", testApp)
+	before := testutil.ToFloat64(flips)
+
+	// One navIdent evaluated as different users, each with a stable result, must not count as flips
+	steps := []struct {
+		body      string
+		enabled   bool
+		wantFlips float64
+	}{
+		{body: `"seed":"seed-1"`, enabled: true, wantFlips: 0},
+		{body: `"seed":"seed-2"`, enabled: false, wantFlips: 0},
+		{body: `"seed":"seed-1"`, enabled: true, wantFlips: 0},
+		{body: `"unleashContext":{"userId":"Z200002"}`, enabled: false, wantFlips: 0},
+		{body: `"unleashContext":{"userId":"Z200003"}`, enabled: true, wantFlips: 0},
+		{body: `"seed":"seed-2"`, enabled: true, wantFlips: 1},
+	}
+
+	for i, step := range steps {
+		evaluator.Features = map[string]bool{"flip-seeded": step.enabled}
+		body := `{"appName":"` + testApp + `","navIdent":"Z200001",` + step.body + `}`
+		if got := decodeResponse(t, checkFeature(PathPrefix+"flip-seeded", strings.NewReader(body))); got.Enabled != step.enabled {
+			t.Fatalf("step %d: enabled %v, want %v", i, got.Enabled, step.enabled)
+		}
+		if got := testutil.ToFloat64(flips) - before; got != step.wantFlips {
+			t.Errorf("step %d: feature_flips_total increased by %v, want %v", i, got, step.wantFlips)
+		}
+	}
+}
+
+func TestHandlerNormalizesFeatureNames(t *testing.T) {
+	previous := nameNormalizer
+	nameNormalizer = newNameNormalizer("-", "._")
+	t.Cleanup(func() { nameNormalizer = previous })
+	withEvaluator(t, map[string]bool{"new-ui-enabled": true})
+
+	tests := []struct {
+		name        string
+		wantEnabled bool
+	}{
+		{name: "new-ui-enabled", wantEnabled: true},
+		{name: "new.ui.enabled", wantEnabled: true},
+		{name: "new_ui.enabled", wantEnabled: true},
+		{name: "newuienabled", wantEnabled: false},
+		{name: "new~ui~enabled", wantEnabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := checkFeature(PathPrefix+tt.name, strings.NewReader(`{"appName":"`+testApp+`"}`))
+			if got := decodeResponse(t, w); got.Enabled != tt.wantEnabled {
+				t.Errorf("enabled %v, want %v", got.Enabled, tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestNewNameNormalizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		canonical string
+		aliases   string
+		input     string
+		want      string
+	}{
+		{name: "disabled", canonical: "", aliases: ".", input: "new.ui", want: "new.ui"},
+		{name: "no aliases", canonical: "-", aliases: "", input: "new.ui", want: "new.ui"},
+		{name: "only the canonical separator", canonical: "-", aliases: "-", input: "new.ui", want: "new.ui"},
+		{name: "aliases", canonical: "-", aliases: "._", input: "new.ui_v2", want: "new-ui-v2"},
+		{name: "canonical among aliases", canonical: ".", aliases: "-.", input: "new-ui.v2", want: "new.ui.v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.input
+			if normalizer := newNameNormalizer(tt.canonical, tt.aliases); normalizer != nil {
+				got = normalizer.Replace(tt.input)
+			}
+			if got != tt.want {
+				t.Errorf("normalized %q = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerFlagsDeprecatedFeatures(t *testing.T) {
+	clientstest.SetForTest(t, &env.DeprecatedFeatures, []string{"old-ui"})
+	withEvaluator(t, map[string]bool{"old-ui": true, "new-ui": true})
+
+	tests := []struct {
+		feature         string
+		wantDeprecation string
+	}{
+		{feature: "old-ui", wantDeprecation: "true"},
+		{feature: "new-ui", wantDeprecation: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.feature, func(t *testing.T) {
+			w := checkFeature(PathPrefix+tt.feature, strings.NewReader(`{"appName":"`+testApp+`"}`))
+			if got := decodeResponse(t, w); !got.Enabled {
+				t.Errorf("enabled %v, want deprecated features still evaluated", got.Enabled)
+			}
+			if got := w.Header().Get("Deprecation"); got != tt.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tt.wantDeprecation)
+			}
+		})
+	}
+}
+
+func TestRequestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Request
+	}{
+		{
+			name: "camelCase",
+			body: `{"navIdent":"Z123456","appName":"kabal-api","podName":"pod-1","currentTime":"2026-01-01T00:00:00Z"}`,
+			want: Request{NavIdent: "Z123456", AppName: "kabal-api", PodName: "pod-1", CurrentTime: "2026-01-01T00:00:00Z"},
+		},
+		{
+			name: "snake_case",
+			body: `{"nav_ident":"Z123456","app_name":"kabal-api","pod_name":"pod-1","current_time":"2026-01-01T00:00:00Z"}`,
+			want: Request{NavIdent: "Z123456", AppName: "kabal-api", PodName: "pod-1", CurrentTime: "2026-01-01T00:00:00Z"},
+		},
+		{
+			name: "camelCase wins",
+			body: `{"appName":"kabal-api","app_name":"kabal-frontend","nav_ident":"Z123456"}`,
+			want: Request{NavIdent: "Z123456", AppName: "kabal-api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Request
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if got.NavIdent != tt.want.NavIdent || got.AppName != tt.want.AppName || got.PodName != tt.want.PodName || got.CurrentTime != tt.want.CurrentTime {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerAcceptsSnakeCaseBody(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+	w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"app_name":"`+testApp+`","nav_ident":"Z123456","pod_name":"pod-1"}`))
+
+	if got := decodeResponse(t, w); !got.Enabled {
+		t.Errorf("enabled %v, want true", got.Enabled)
+	}
+	ctx := evaluator.Contexts()[0]
+	if ctx.UserId != "Z123456" || ctx.Properties["podName"] != "pod-1" {
+		t.Errorf("evaluated with user %q and podName %q, want Z123456 and pod-1", ctx.UserId, ctx.Properties["podName"])
+	}
+}
+
+func TestHandlerUnleashContextOverride(t *testing.T) {
+	derived := unleashcontext.Context{
+		UserId:        "Z123456",
+		RemoteAddress: "192.0.2.10",
+		Environment:   clients.Environment(testApp),
+		AppName:       testApp,
+		Properties:    map[string]string{"podName": "pod-1", "unitId": "42"},
+	}
+
+	tests := []struct {
+		name     string
+		override string
+		want     unleashcontext.Context
+	}{
+		{
+			name:     "full",
+			override: `{"userId":"Z654321","sessionId":"session-1","remoteAddress":"198.51.100.7","environment":"test","appName":"other-app","currentTime":"2026-01-20T15:33:00Z","properties":{"unitId":"43","region":"gcp"}}`,
+			want: unleashcontext.Context{
+				UserId:        "Z654321",
+				SessionId:     "session-1",
+				RemoteAddress: "198.51.100.7",
+				Environment:   "test",
+				AppName:       "other-app",
+				CurrentTime:   "2026-01-20T15:33:00Z",
+				Properties:    map[string]string{"podName": "pod-1", "unitId": "43", "region": "gcp"},
+			},
+		},
+		{
+			name:     "partial",
+			override: `{"userId":"Z654321","properties":{"region":"gcp"}}`,
+			want: unleashcontext.Context{
+				UserId:        "Z654321",
+				RemoteAddress: derived.RemoteAddress,
+				Environment:   derived.Environment,
+				AppName:       derived.AppName,
+				Properties:    map[string]string{"podName": "pod-1", "unitId": "42", "region": "gcp"},
+			},
+		},
+		{
+			name:     "empty",
+			override: `{}`,
+			want:     derived,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			body := `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-1","properties":{"unitId":"42"},"unleashContext":` + tt.override + `}`
+			r := httptest.NewRequest(http.MethodPost, PathPrefix+"new-ui", strings.NewReader(body))
+			r.RemoteAddr = "192.0.2.10:4321"
+			decodeResponse(t, serveFeature(r))
+
+			if got := evaluator.Contexts()[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("context = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerRemoteAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		trusted    bool
+		remoteAddr string
+		value      string
+		want       string
+	}{
+		{name: "connection address", remoteAddr: "192.0.2.10:4321", want: "192.0.2.10"},
+		{name: "IPv6 connection address", remoteAddr: "[2001:db8::1]:4321", want: "2001:db8::1"},
+		{name: "header not configured", trusted: true, remoteAddr: "10.0.0.1:4321", value: "198.51.100.7", want: "10.0.0.1"},
+		{name: "header from trusted proxy", header: "X-Forwarded-For", trusted: true, remoteAddr: "10.0.0.1:4321", value: "198.51.100.7", want: "198.51.100.7"},
+		{name: "first of a list", header: "X-Forwarded-For", trusted: true, remoteAddr: "10.0.0.1:4321", value: " 198.51.100.7 , 10.0.0.2", want: "198.51.100.7"},
+		{name: "header from untrusted client", header: "X-Forwarded-For", remoteAddr: "10.0.0.1:4321", value: "198.51.100.7", want: "10.0.0.1"},
+		{name: "invalid header value", header: "X-Real-IP", trusted: true, remoteAddr: "10.0.0.1:4321", value: "unknown", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.ClientIPHeader, tt.header)
+			if tt.trusted {
+				t.Cleanup(trust.Trust("10.0.0.0/8"))
+			}
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			r := httptest.NewRequest(http.MethodPost, PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`))
+			r.RemoteAddr = tt.remoteAddr
+			if tt.value != "" {
+				r.Header.Set("X-Forwarded-For", tt.value)
+				r.Header.Set("X-Real-IP", tt.value)
+			}
+			decodeResponse(t, serveFeature(r))
+
+			if got := evaluator.Contexts()[0].RemoteAddress; got != tt.want {
+				t.Errorf("RemoteAddress = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// appearingEvaluator is a clients.Evaluator for which a feature is unknown until it has been evaluated a number of times,
+// like a feature briefly missing while the SDK swaps in a refreshed repository.
+type appearingEvaluator struct {
+	*clientstest.Evaluator
+	unknownFor int
+}
+
+func (e *appearingEvaluator) Evaluate(name string, ctx unleashcontext.Context) (bool, bool) {
+	enabled, known := e.Evaluator.Evaluate(name, ctx)
+	if e.Evaluations() <= e.unknownFor {
+		return false, false
+	}
+	return enabled, known
+}
+
+func TestHandlerRetriesUnknownFeatures(t *testing.T) {
+	tests := []struct {
+		name            string
+		retryDelay      time.Duration
+		unknownFor      int
+		wantEnabled     bool
+		wantEvaluations int
+		wantRetry       string
+	}{
+		{name: "retry disabled", unknownFor: 1, wantEnabled: false, wantEvaluations: 1},
+		{name: "known feature", retryDelay: time.Millisecond, unknownFor: 0, wantEnabled: true, wantEvaluations: 1},
+		{name: "recovered", retryDelay: time.Millisecond, unknownFor: 1, wantEnabled: true, wantEvaluations: 2, wantRetry: "true"},
+		{name: "still unknown", retryDelay: time.Millisecond, unknownFor: 2, wantEnabled: false, wantEvaluations: 2, wantRetry: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &evaluationRetryDelay, tt.retryDelay)
+			evaluator := &appearingEvaluator{Evaluator: clientstest.NewEvaluator(map[string]bool{"new-ui": true}), unknownFor: tt.unknownFor}
+			clients.RegisterEvaluator(testApp, evaluator)
+			t.Cleanup(func() { clients.UnregisterEvaluator(testApp) })
+
+			label := featureLabel("new-ui", tt.wantRetry == "true")
+			retries := metrics.FeatureEvaluationRetries.WithLabelValues(label, tt.wantRetry)
+			before := testutil.ToFloat64(retries)
+
+			w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`))
+
+			if got := decodeResponse(t, w); got.Enabled != tt.wantEnabled {
+				t.Errorf("enabled %v, want %v", got.Enabled, tt.wantEnabled)
+			}
+			if got := evaluator.Evaluations(); got != tt.wantEvaluations {
+				t.Errorf("%d evaluations, want %d", got, tt.wantEvaluations)
+			}
+			if tt.wantRetry != "" {
+				if got := testutil.ToFloat64(retries) - before; got != 1 {
+					t.Errorf("feature_evaluation_retries_total{recovered=%s} increased by %v, want 1", tt.wantRetry, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerMeta(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	known, unknown := true, false
+	tests := []struct {
+		name      string
+		path      string
+		wantKnown *bool
+	}{
+		{name: "without meta", path: PathPrefix + "new-ui"},
+		{name: "meta false", path: PathPrefix + "new-ui?meta=false"},
+		{name: "known", path: PathPrefix + "new-ui?meta=true", wantKnown: &known},
+		{name: "unknown", path: PathPrefix + "missing-ui?meta=true", wantKnown: &unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := checkFeature(tt.path, strings.NewReader(`{"appName":"`+testApp+`"}`))
+			got := decodeResponse(t, w).Known
+			if (got == nil) != (tt.wantKnown == nil) || (got != nil && *got != *tt.wantKnown) {
+				t.Errorf("known %v, want %v", fmtBool(got), fmtBool(tt.wantKnown))
+			}
+		})
+	}
+}
+
+// fmtBool formats an optional bool for test messages.
+func fmtBool(b *bool) string {
+	if b == nil {
+		return "omitted"
+	}
+	return fmt.Sprint(*b)
+}
+
+func TestHandlerAnonymousSession(t *testing.T) {
+	tests := []struct {
+		name          string
+		cookieName    string
+		cookie        string
+		seed          string
+		wantSession   string
+		wantSetCookie bool
+	}{
+		{name: "disabled", cookie: "abc", wantSession: ""},
+		{name: "new session", cookieName: "unleash-session", wantSetCookie: true},
+		{name: "existing session", cookieName: "unleash-session", cookie: "abc", wantSession: "abc"},
+		{name: "seed wins", cookieName: "unleash-session", cookie: "abc", seed: "seed-1", wantSession: "seed-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.AnonymousSessionCookie, tt.cookieName)
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			r := httptest.NewRequest(http.MethodPost, PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","seed":"`+tt.seed+`"}`))
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "unleash-session", Value: tt.cookie})
+			}
+			w := serveFeature(r)
+			decodeResponse(t, w)
+
+			session := evaluator.Contexts()[0].SessionId
+			cookies := w.Result().Cookies()
+			if !tt.wantSetCookie {
+				if session != tt.wantSession {
+					t.Errorf("SessionId = %q, want %q", session, tt.wantSession)
+				}
+				if len(cookies) != 0 {
+					t.Errorf("set cookies %v, want none", cookies)
+				}
+				return
+			}
+
+			if len(cookies) != 1 || cookies[0].Name != tt.cookieName {
+				t.Fatalf("set cookies %v, want %s", cookies, tt.cookieName)
+			}
+			cookie := cookies[0]
+			if session == "" || cookie.Value != session {
+				t.Errorf("SessionId = %q, cookie value %q, want the new session ID in both", session, cookie.Value)
+			}
+			if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge != sessionCookieMaxAge {
+				t.Errorf("cookie %+v, want HttpOnly, Secure, SameSite=Lax and a one year max age", cookie)
+			}
+		})
+	}
+}
+
+func TestHandlerInvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		inverted []string
+		enabled  bool
+		want     bool
+	}{
+		{name: "not inverted", path: PathPrefix + "new-ui", enabled: true, want: true},
+		{name: "query", path: PathPrefix + "new-ui?invert=true", enabled: true, want: false},
+		{name: "query false", path: PathPrefix + "new-ui?invert=false", enabled: true, want: true},
+		{name: "disabled query", path: PathPrefix + "new-ui?invert=true", enabled: false, want: true},
+		{name: "INVERTED_FEATURES", path: PathPrefix + "new-ui", inverted: []string{"new-ui"}, enabled: true, want: false},
+		{name: "other feature inverted", path: PathPrefix + "new-ui", inverted: []string{"old-ui"}, enabled: true, want: true},
+		{name: "both", path: PathPrefix + "new-ui?invert=true", inverted: []string{"new-ui"}, enabled: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.InvertedFeatures, tt.inverted)
+			withEvaluator(t, map[string]bool{"new-ui": tt.enabled})
+
+			requests := metrics.FeatureRequestsTotal.WithLabelValues("new-ui", testApp, fmt.Sprint(tt.enabled))
+			before := testutil.ToFloat64(requests)
+
+			w := checkFeature(tt.path, strings.NewReader(`{"appName":"`+testApp+`"}`))
+
+			if got := decodeResponse(t, w); got.Enabled != tt.want {
+				t.Errorf("enabled %v, want %v", got.Enabled, tt.want)
+			}
+			// Metrics keep the evaluated value, only the response is inverted
+			if got := testutil.ToFloat64(requests) - before; got != 1 {
+				t.Errorf("feature_requests_total{enabled=%t} increased by %v, want 1", tt.enabled, got)
+			}
+		})
+	}
+}
+
+func TestHandlerGet(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantUser   string
+		wantPod    string
+		wantTime   string
+		wantCode   string
+	}{
+		{name: "app only", query: "appName=" + testApp, wantStatus: http.StatusOK},
+		{
+			name:       "all fields",
+			query:      "appName=" + testApp + "&navIdent=Z123456&podName=pod-1&currentTime=2026-01-01T00%3A00%3A00Z",
+			wantStatus: http.StatusOK,
+			wantUser:   "Z123456",
+			wantPod:    "pod-1",
+			wantTime:   "2026-01-01T00:00:00Z",
+		},
+		{name: "missing app", query: "navIdent=Z123456", wantStatus: http.StatusBadRequest, wantCode: "missing_app_name"},
+		{name: "invalid current time", query: "appName=" + testApp + "&currentTime=yesterday", wantStatus: http.StatusBadRequest, wantCode: "invalid_current_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			w := serveFeature(httptest.NewRequest(http.MethodGet, PathPrefix+"new-ui?"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "private, max-age=0" {
+				t.Errorf("Cache-Control = %q, want private, max-age=0 while the response cache is disabled", got)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var response ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != tt.wantCode {
+					t.Errorf("response %+v, err %v, want code %s", response, err, tt.wantCode)
+				}
+				return
+			}
+
+			if got := decodeResponse(t, w); !got.Enabled {
+				t.Errorf("enabled %v, want true", got.Enabled)
+			}
+			ctx := evaluator.Contexts()[0]
+			if ctx.UserId != tt.wantUser || ctx.Properties["podName"] != tt.wantPod || ctx.CurrentTime != tt.wantTime {
+				t.Errorf("evaluated with user %q, podName %q, currentTime %q, want %q, %q, %q",
+					ctx.UserId, ctx.Properties["podName"], ctx.CurrentTime, tt.wantUser, tt.wantPod, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestHandlerCurrentTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		currentTime string
+		wantStatus  int
+	}{
+		{name: "unset", currentTime: "", wantStatus: http.StatusOK},
+		{name: "UTC", currentTime: "2026-01-01T00:00:00Z", wantStatus: http.StatusOK},
+		{name: "offset", currentTime: "2026-06-01T12:30:00+02:00", wantStatus: http.StatusOK},
+		{name: "date only", currentTime: "2026-01-01", wantStatus: http.StatusBadRequest},
+		{name: "not a time", currentTime: "now", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","currentTime":"`+tt.currentTime+`"}`))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var response ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != "invalid_current_time" {
+					t.Errorf("response %+v, err %v, want code invalid_current_time", response, err)
+				}
+				if evaluator.Evaluations() != 0 {
+					t.Errorf("%d evaluations, want none for an invalid currentTime", evaluator.Evaluations())
+				}
+				return
+			}
+			if got := evaluator.Contexts()[0].CurrentTime; got != tt.currentTime {
+				t.Errorf("CurrentTime = %q, want %q", got, tt.currentTime)
+			}
+		})
+	}
+}
+
+func TestHandlerResponseCache(t *testing.T) {
+	const first = `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-1"}`
+
+	tests := []struct {
+		name            string
+		ttl             time.Duration
+		second          string
+		wait            time.Duration
+		wantEvaluations int
+	}{
+		{name: "disabled", ttl: 0, second: first, wantEvaluations: 2},
+		{name: "identical request", ttl: time.Minute, second: first, wantEvaluations: 1},
+		{name: "other user", ttl: time.Minute, second: `{"appName":"` + testApp + `","navIdent":"Z654321","podName":"pod-1"}`, wantEvaluations: 2},
+		{name: "other pod", ttl: time.Minute, second: `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-2"}`, wantEvaluations: 2},
+		{name: "seed", ttl: time.Minute, second: `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-1","seed":"1"}`, wantEvaluations: 2},
+		{name: "properties", ttl: time.Minute, second: `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-1","properties":{"unitId":"42"}}`, wantEvaluations: 2},
+		{name: "expired", ttl: 20 * time.Millisecond, second: first, wait: 50 * time.Millisecond, wantEvaluations: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &cacheTTL, tt.ttl)
+			responseCache.removeApp(testApp)
+			t.Cleanup(func() { responseCache.removeApp(testApp) })
+			evaluator := withEvaluator(t, map[string]bool{"cached-ui": true})
+
+			hits := metrics.FeatureCacheHits.WithLabelValues("cached-ui")
+			before := testutil.ToFloat64(hits)
+
+			for i, body := range []string{first, tt.second} {
+				if i == 1 {
+					time.Sleep(tt.wait)
+				}
+				if got := decodeResponse(t, checkFeature(PathPrefix+"cached-ui", strings.NewReader(body))); !got.Enabled {
+					t.Fatalf("request %d: enabled %v, want true", i, got.Enabled)
+				}
+			}
+
+			if got := evaluator.Evaluations(); got != tt.wantEvaluations {
+				t.Errorf("%d evaluations, want %d", got, tt.wantEvaluations)
+			}
+			if got, want := testutil.ToFloat64(hits)-before, float64(2-tt.wantEvaluations); got != want {
+				t.Errorf("feature_cache_hits_total increased by %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandlerRequireKnownFeature(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+	const body = `{"appName":"` + testApp + `","navIdent":"Z123456"}`
+
+	t.Run("disabled", func(t *testing.T) {
+		clientstest.SetForTest(t, &env.RequireKnownFeature, false)
+		if got := decodeResponse(t, checkFeature(PathPrefix+"retired-ui", strings.NewReader(body))); got.Enabled {
+			t.Error("unknown feature enabled, want disabled")
+		}
+	})
+
+	t.Run("known feature", func(t *testing.T) {
+		clientstest.SetForTest(t, &env.RequireKnownFeature, true)
+		if got := decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(body))); !got.Enabled {
+			t.Error("known feature disabled, want enabled")
+		}
+	})
+
+	t.Run("unknown feature", func(t *testing.T) {
+		clientstest.SetForTest(t, &env.RequireKnownFeature, true)
+		errors := metrics.FeatureRequestErrors.WithLabelValues("unknown_feature")
+		before := testutil.ToFloat64(errors)
+
+		w := checkFeature(PathPrefix+"retired-ui", strings.NewReader(body))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status %d, want %d", w.Code, http.StatusNotFound)
+		}
+		var response ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if response.Code != "unknown_feature" {
+			t.Errorf("code = %q, want unknown_feature", response.Code)
+		}
+		if got := testutil.ToFloat64(errors) - before; got != 1 {
+			t.Errorf("feature_request_errors_total{error_type=unknown_feature} increased by %v, want 1", got)
+		}
+	})
+}
+
+func TestHandlerSeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		seed        string
+		wantStatus  int
+		wantUser    string
+		wantSession string
+	}{
+		{name: "unset", seed: "", wantStatus: http.StatusOK, wantUser: "Z123456", wantSession: ""},
+		{name: "set", seed: "case-42", wantStatus: http.StatusOK, wantUser: "case-42", wantSession: "case-42"},
+		{name: "longest", seed: strings.Repeat("x", maxSeedLength), wantStatus: http.StatusOK, wantUser: strings.Repeat("x", maxSeedLength), wantSession: strings.Repeat("x", maxSeedLength)},
+		{name: "too long", seed: strings.Repeat("x", maxSeedLength+1), wantStatus: http.StatusBadRequest},
+		{name: "leading whitespace", seed: " case-42", wantStatus: http.StatusBadRequest},
+		{name: "trailing whitespace", seed: "case-42\t", wantStatus: http.StatusBadRequest},
+		{name: "whitespace only", seed: " ", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &env.AnonymousSessionCookie, "")
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			body, _ := json.Marshal(map[string]string{"appName": testApp, "navIdent": "Z123456", "seed": tt.seed})
+			w := checkFeature(PathPrefix+"new-ui", bytes.NewReader(body))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var response ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != "invalid_seed" {
+					t.Errorf("response %+v, err %v, want code invalid_seed", response, err)
+				}
+				if evaluator.Evaluations() != 0 {
+					t.Errorf("%d evaluations, want none for an invalid seed", evaluator.Evaluations())
+				}
+				return
+			}
+			ctx := evaluator.Contexts()[0]
+			if ctx.UserId != tt.wantUser || ctx.SessionId != tt.wantSession {
+				t.Errorf("evaluated with user %q and session %q, want %q and %q", ctx.UserId, ctx.SessionId, tt.wantUser, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestHandlerQuery(t *testing.T) {
+	tests := []struct {
+		name                string
+		query               string
+		body                string
+		wantContentLocation string
+	}{
+		{
+			name:                "app only",
+			body:                `{"appName":"` + testApp + `"}`,
+			wantContentLocation: PathPrefix + "new-ui?appName=" + testApp,
+		},
+		{
+			name:                "all parameters",
+			body:                `{"appName":"` + testApp + `","navIdent":"Z123456","podName":"pod-1","currentTime":"2026-01-01T00:00:00Z","seed":"case 42"}`,
+			wantContentLocation: PathPrefix + "new-ui?appName=" + testApp + "&currentTime=2026-01-01T00%3A00%3A00Z&navIdent=Z123456&podName=pod-1&seed=case+42",
+		},
+		{
+			name:                "invert and meta",
+			query:               "?invert=true&meta=true",
+			body:                `{"appName":"` + testApp + `"}`,
+			wantContentLocation: PathPrefix + "new-ui?appName=" + testApp + "&invert=true&meta=true",
+		},
+		{
+			name:                "invert false",
+			query:               "?invert=false",
+			body:                `{"appName":"` + testApp + `"}`,
+			wantContentLocation: PathPrefix + "new-ui?appName=" + testApp,
+		},
+		{
+			name: "properties",
+			body: `{"appName":"` + testApp + `","properties":{"unitId":"42"}}`,
+		},
+		{
+			name: "unleash context",
+			body: `{"appName":"` + testApp + `","unleashContext":{"userId":"Z654321"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEvaluator(t, map[string]bool{"new-ui": true})
+
+			w := serveFeature(httptest.NewRequest("QUERY", PathPrefix+"new-ui"+tt.query, strings.NewReader(tt.body)))
+			decodeResponse(t, w)
+
+			if got := w.Header().Get("Cache-Control"); got != "private, max-age=0" {
+				t.Errorf("Cache-Control = %q, want private, max-age=0 while the response cache is disabled", got)
+			}
+			if got := w.Header().Get("Content-Location"); got != tt.wantContentLocation {
+				t.Errorf("Content-Location = %q, want %q", got, tt.wantContentLocation)
+			}
+		})
+	}
+}
+
+func TestHandlerQueryCacheControl(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+	clientstest.SetForTest(t, &cacheTTL, 1500*time.Millisecond)
+
+	body := `{"appName":"` + testApp + `"}`
+
+	w := serveFeature(httptest.NewRequest("QUERY", PathPrefix+"new-ui", strings.NewReader(body)))
+	decodeResponse(t, w)
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=2" {
+		t.Errorf("QUERY Cache-Control = %q, want private, max-age=2 for FEATURE_CACHE_TTL 1.5s", got)
+	}
+
+	// Unlike QUERY, POST responses are not cacheable
+	w = checkFeature(PathPrefix+"new-ui", strings.NewReader(body))
+	decodeResponse(t, w)
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("POST Cache-Control = %q, want none", got)
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	for _, method := range []string{http.MethodPut, http.MethodDelete, http.MethodPatch} {
+		t.Run(method, func(t *testing.T) {
+			w := serveFeature(httptest.NewRequest(method, PathPrefix+"new-ui", nil))
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+			}
+			if got := w.Header().Get("Allow"); got != "GET, POST, QUERY" {
+				t.Errorf("Allow = %q, want %q", got, "GET, POST, QUERY")
+			}
+		})
+	}
+}
+
+func TestHandlerProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantStatus int
+		want       string
+	}{
+		{name: "string", value: `"42"`, wantStatus: http.StatusOK, want: "42"},
+		{name: "integer", value: `42`, wantStatus: http.StatusOK, want: "42"},
+		{name: "float", value: `4.20`, wantStatus: http.StatusOK, want: "4.20"},
+		{name: "exponent", value: `1e3`, wantStatus: http.StatusOK, want: "1e3"},
+		{name: "boolean", value: `true`, wantStatus: http.StatusOK, want: "true"},
+		{name: "null", value: `null`, wantStatus: http.StatusBadRequest},
+		{name: "object", value: `{"id":42}`, wantStatus: http.StatusBadRequest},
+		{name: "array", value: `[42]`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","properties":{"unitId":`+tt.value+`}}`))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var response ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != "invalid_properties" {
+					t.Errorf("response %+v, err %v, want code invalid_properties", response, err)
+				}
+				return
+			}
+			if got := evaluator.Contexts()[0].Properties["unitId"]; got != tt.want {
+				t.Errorf("unitId = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerKeepsPodNameWithAnyProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string
+		want       map[string]string
+	}{
+		{name: "null properties", properties: `"properties":null`, want: map[string]string{"podName": "pod-1"}},
+		{name: "empty properties", properties: `"properties":{}`, want: map[string]string{"podName": "pod-1"}},
+		{name: "populated properties", properties: `"properties":{"unitId":"42"}`, want: map[string]string{"podName": "pod-1", "unitId": "42"}},
+		{name: "null unleashContext properties", properties: `"unleashContext":{"properties":null}`, want: map[string]string{"podName": "pod-1"}},
+		{name: "empty unleashContext properties", properties: `"unleashContext":{"properties":{}}`, want: map[string]string{"podName": "pod-1"}},
+		{name: "populated unleashContext properties", properties: `"unleashContext":{"properties":{"unitId":"42"}}`, want: map[string]string{"podName": "pod-1", "unitId": "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+			body := `{"appName":"` + testApp + `","podName":"pod-1",` + tt.properties + `}`
+			decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(body)))
+
+			if got := evaluator.Contexts()[0].Properties; !maps.Equal(got, tt.want) {
+				t.Errorf("properties = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerDefaultPropertiesPrecedence(t *testing.T) {
+	defaults, err := parseDefaultProperties(`{"platform":"nais","unitId":"default","region":"default","podName":"default-pod"}`)
+	if err != nil {
+		t.Fatalf("parseDefaultProperties: %v", err)
+	}
+	clientstest.SetForTest(t, &defaultProperties, defaults)
+	evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+
+	body := `{"appName":"` + testApp + `","podName":"pod-1","properties":{"unitId":"42","region":"request"},"unleashContext":{"properties":{"region":"override"}}}`
+	decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(body)))
+
+	// default < request properties < podName < unleashContext properties
+	want := map[string]string{"platform": "nais", "unitId": "42", "podName": "pod-1", "region": "override"}
+	if got := evaluator.Contexts()[0].Properties; !maps.Equal(got, want) {
+		t.Errorf("properties = %v, want %v", got, want)
+	}
+}
+
+func TestHandlerUnleashSpanSampling(t *testing.T) {
+	tests := []struct {
+		name      string
+		ratio     float64
+		wantSpans int
+	}{
+		{name: "always", ratio: 1, wantSpans: 3},
+		{name: "never", ratio: 0, wantSpans: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			clientstest.SetForTest(t, &tracer, trace.Tracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")))
+			clientstest.SetForTest(t, &unleashSpanRatio, tt.ratio)
+			withEvaluator(t, map[string]bool{"new-ui": true})
+
+			for range 3 {
+				decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`)))
+			}
+
+			var handlerSpans, unleashSpans int
+			for _, span := range recorder.Ended() {
+				switch span.Name() {
+				case "featureHandler":
+					handlerSpans++
+				case "unleash.IsEnabled":
+					unleashSpans++
+				}
+			}
+			if handlerSpans != 3 {
+				t.Errorf("%d featureHandler spans, want 3", handlerSpans)
+			}
+			if unleashSpans != tt.wantSpans {
+				t.Errorf("%d unleash.IsEnabled spans, want %d", unleashSpans, tt.wantSpans)
+			}
+		})
+	}
+
+	// Error paths always get a handler span with the error type, even when successful evaluations are not sampled
+	errorTests := []struct {
+		name          string
+		path          string
+		body          string
+		wantErrorType string
+	}{
+		{name: "invalid name", path: PathPrefix + "..", body: `{"appName":"` + testApp + `"}`, wantErrorType: "invalid_feature"},
+		{name: "unknown app", path: PathPrefix + "new-ui", body: `{"appName":"not-an-app"}`, wantErrorType: "unknown_app_name"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			clientstest.SetForTest(t, &tracer, trace.Tracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")))
+			clientstest.SetForTest(t, &unleashSpanRatio, 0.0)
+			withEvaluator(t, map[string]bool{"new-ui": true})
+
+			if w := checkFeature(tt.path, strings.NewReader(tt.body)); w.Code == http.StatusOK {
+				t.Fatalf("status %d, want an error", w.Code)
+			}
+
+			var errorType string
+			for _, span := range recorder.Ended() {
+				if span.Name() != "featureHandler" {
+					continue
+				}
+				for _, attr := range span.Attributes() {
+					if attr.Key == "error.type" {
+						errorType = attr.Value.AsString()
+					}
+				}
+			}
+			if errorType != tt.wantErrorType {
+				t.Errorf("featureHandler span error.type = %q, want %q", errorType, tt.wantErrorType)
+			}
+		})
+	}
+}
+
+func BenchmarkHandlerUnleashSpanSampling(b *testing.B) {
+	for _, ratio := range []float64{1, 0.1, 0} {
+		b.Run(fmt.Sprintf("ratio=%v", ratio), func(b *testing.B) {
+			clientstest.SetForTest(b, &tracer, trace.Tracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder())).Tracer("test")))
+			clientstest.SetForTest(b, &unleashSpanRatio, ratio)
+			withEvaluator(b, map[string]bool{"new-ui": true})
+			body := []byte(`{"appName":"` + testApp + `","navIdent":"Z123456"}`)
+
+			b.ReportAllocs()
+			for b.Loop() {
+				w := checkFeature(PathPrefix+"new-ui", bytes.NewReader(body))
+				if w.Code != http.StatusOK {
+					b.Fatalf("status %d, body %s", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerLogsSlowRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		wantWarn  bool
+	}{
+		{name: "fast", threshold: time.Hour, wantWarn: false},
+		{name: "slow", threshold: 0, wantWarn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientstest.SetForTest(t, &slowThreshold, tt.threshold)
+			withEvaluator(t, map[string]bool{"new-ui": true})
+
+			var buf bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+			t.Cleanup(func() { slog.SetDefault(previous) })
+
+			decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`)))
+
+			if got := strings.Contains(buf.String(), "Feature check for "+testApp+" - new-ui = true"); got != tt.wantWarn {
+				t.Errorf("logged at warn level %v, want %v: %s", got, tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
+
+func TestHandlerRecordsRequestBodySize(t *testing.T) {
+	withEvaluator(t, map[string]bool{"new-ui": true})
+
+	// Gathered through a registry of its own, since the histogram is already registered with the default one
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.FeatureRequestBodySize)
+	histogram := func() (count uint64, sum float64) {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("Gather: %v", err)
+		}
+		h := families[0].GetMetric()[0].GetHistogram()
+		return h.GetSampleCount(), h.GetSampleSum()
+	}
+
+	countBefore, sumBefore := histogram()
+	body := `{"appName":"` + testApp + `","navIdent":"Z123456"}`
+	decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(body)))
+	count, sum := histogram()
+
+	if got := count - countBefore; got != 1 {
+		t.Errorf("feature_request_body_bytes observed %d times, want 1", got)
+	}
+	if got := sum - sumBefore; got != float64(len(body)) {
+		t.Errorf("feature_request_body_bytes observed %v bytes, want %d", got, len(body))
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	// Configure sets all of these, so they are restored after the test
+	clientstest.SetForTest(t, &requestTimeout, requestTimeout)
+	clientstest.SetForTest(t, &maxBodyBytes, maxBodyBytes)
+	clientstest.SetForTest(t, &slowThreshold, slowThreshold)
+	clientstest.SetForTest(t, &maxBatchSize, maxBatchSize)
+	clientstest.SetForTest(t, &maxProperties, maxProperties)
+	clientstest.SetForTest(t, &cacheTTL, cacheTTL)
+	clientstest.SetForTest(t, &staleResultsAfter, staleResultsAfter)
+	clientstest.SetForTest(t, &evaluationRetryDelay, evaluationRetryDelay)
+	clientstest.SetForTest(t, &killSwitchTTL, killSwitchTTL)
+	clientstest.SetForTest(t, &statsWindow, statsWindow)
+	clientstest.SetForTest(t, &statsMaxFeatures, statsMaxFeatures)
+	clientstest.SetForTest(t, &shadowSlots, shadowSlots)
+
+	clientstest.SetForTest(t, &env.FeatureRequestTimeout, "5s")
+	clientstest.SetForTest(t, &env.MaxBatchSize, "many")
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	Configure()
+
+	if requestTimeout != 5*time.Second {
+		t.Errorf("requestTimeout = %v, want FEATURE_REQUEST_TIMEOUT", requestTimeout)
+	}
+	if maxBatchSize != 50 {
+		t.Errorf("maxBatchSize = %d, want the default for an invalid MAX_BATCH_SIZE", maxBatchSize)
+	}
+	if cap(shadowSlots) != 16 {
+		t.Errorf("%d shadow slots, want the default", cap(shadowSlots))
+	}
+
+	var record struct {
+		Msg string `json:"msg"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decoding warning: %v: %s", err, buf.String())
+	}
+	if !strings.Contains(record.Msg, "MAX_BATCH_SIZE") {
+		t.Errorf("logged %q, want a warning about MAX_BATCH_SIZE", record.Msg)
+	}
+}
+
+func TestHandlerTimesOut(t *testing.T) {
+	clientstest.SetForTest(t, &requestTimeout, 10*time.Millisecond)
+	evaluator := clientstest.NewBlockingEvaluator(map[string]bool{"slow-feature": true})
+	clients.RegisterEvaluator(testApp, evaluator)
+	t.Cleanup(func() { clients.UnregisterEvaluator(testApp) })
+	sink, stop := withDecisionSink(t, http.StatusAccepted, "")
+
+	// A previous result that differs, so a tracked evaluation would count as a flip
+	trackFlip(testApp, "slow-feature", "Z123456", false)
+	flips := metrics.FeatureFlipsTotal.WithLabelValues("slow-feature", testApp)
+	requests := metrics.FeatureRequestsTotal.WithLabelValues("slow-feature", testApp, "true")
+	flipsBefore, requestsBefore := testutil.ToFloat64(flips), testutil.ToFloat64(requests)
+
+	w := checkFeature(PathPrefix+"slow-feature", strings.NewReader(`{"appName":"`+testApp+`","navIdent":"Z123456"}`))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "timeout" {
+		t.Errorf("code = %q, want timeout", response.Code)
+	}
+
+	// Let the abandoned evaluation finish, and give it time to record anything it should not
+	close(evaluator.Release)
+	deadline := time.Now().Add(5 * time.Second)
+	for evaluator.Evaluations() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned evaluation not finished within 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	if got := testutil.ToFloat64(flips) - flipsBefore; got != 0 {
+		t.Errorf("feature_flips_total increased by %v after the timeout, want 0", got)
+	}
+	if got := testutil.ToFloat64(requests) - requestsBefore; got != 0 {
+		t.Errorf("feature_requests_total increased by %v after the timeout, want 0", got)
+	}
+	if posted := sink.posted(); len(posted) != 0 {
+		t.Errorf("posted decisions %v after the timeout, want none", posted)
+	}
+}
@@ -0,0 +1,98 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/logging"
+)
+
+// Evaluation describes a completed feature evaluation passed to hooks.
+type Evaluation struct {
+	Feature string
+	Context unleashcontext.Context
+	Enabled bool
+	// Known is whether the feature is known to Unleash.
+	Known bool
+}
+
+// EvaluationHook runs custom logic after a feature has been evaluated.
+// It returns the result to use, which may override the evaluated one.
+// If a hook returns an error or panics, its result is ignored and the previous result is kept.
+type EvaluationHook interface {
+	Name() string
+	AfterEvaluate(ctx context.Context, evaluation Evaluation) (bool, error)
+}
+
+var hooks []EvaluationHook
+
+// RegisterHook registers a hook to run after every evaluation, in registration order.
+// Call this at startup, before the server starts handling requests.
+func RegisterHook(hook EvaluationHook) {
+	hooks = append(hooks, hook)
+}
+
+// runHooks passes the evaluation through all registered hooks and returns the final result.
+func runHooks(ctx context.Context, evaluation Evaluation) bool {
+	for _, hook := range hooks {
+		enabled, err := runHook(ctx, hook, evaluation)
+		if err != nil {
+			logging.FromContext(ctx).Error("Evaluation hook "+hook.Name()+" failed",
+				"hook", hook.Name(),
+				"feature", evaluation.Feature,
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		if enabled != evaluation.Enabled {
+			logging.FromContext(ctx).Info(fmt.Sprintf("Evaluation hook %s overrode %s = %t", hook.Name(), evaluation.Feature, enabled),
+				"hook", hook.Name(),
+				"feature", evaluation.Feature,
+				"enabled", enabled,
+			)
+		}
+
+		evaluation.Enabled = enabled
+	}
+
+	return evaluation.Enabled
+}
+
+// runHook runs a single hook, converting a panic into an error.
+func runHook(ctx context.Context, hook EvaluationHook, evaluation Evaluation) (enabled bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return hook.AfterEvaluate(ctx, evaluation)
+}
+
+// ForceEnabledHook enables every feature for a fixed list of users, e.g. test users.
+type ForceEnabledHook struct {
+	navIdents []string
+}
+
+// NewForceEnabledHook creates a ForceEnabledHook for the given navIdents.
+func NewForceEnabledHook(navIdents []string) *ForceEnabledHook {
+	return &ForceEnabledHook{
+		navIdents: navIdents,
+	}
+}
+
+// Name returns the name of the hook
+func (h *ForceEnabledHook) Name() string {
+	return "force_enabled"
+}
+
+// AfterEvaluate enables the feature if the user is in the list of forced users
+func (h *ForceEnabledHook) AfterEvaluate(_ context.Context, evaluation Evaluation) (bool, error) {
+	if evaluation.Context.UserId != "" && slices.Contains(h.navIdents, evaluation.Context.UserId) {
+		return true, nil
+	}
+	return evaluation.Enabled, nil
+}
@@ -0,0 +1,91 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+)
+
+// hookFunc adapts a function to an EvaluationHook.
+type hookFunc func(evaluation Evaluation) (bool, error)
+
+func (f hookFunc) Name() string { return "test" }
+
+func (f hookFunc) AfterEvaluate(_ context.Context, evaluation Evaluation) (bool, error) {
+	return f(evaluation)
+}
+
+// withHooks registers the given hooks in place of any others for the duration of the test.
+func withHooks(t *testing.T, registered ...EvaluationHook) {
+	t.Helper()
+	previous := hooks
+	hooks = nil
+	t.Cleanup(func() { hooks = previous })
+	for _, hook := range registered {
+		RegisterHook(hook)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	passThrough := hookFunc(func(evaluation Evaluation) (bool, error) { return evaluation.Enabled, nil })
+	forceOn := hookFunc(func(Evaluation) (bool, error) { return true, nil })
+	forceOff := hookFunc(func(Evaluation) (bool, error) { return false, nil })
+	failing := hookFunc(func(Evaluation) (bool, error) { return true, errors.New("boom") })
+	panicking := hookFunc(func(Evaluation) (bool, error) { panic("boom") })
+
+	tests := []struct {
+		name    string
+		hooks   []EvaluationHook
+		enabled bool
+		want    bool
+	}{
+		{name: "no hooks", enabled: true, want: true},
+		{name: "pass-through keeps enabled", hooks: []EvaluationHook{passThrough}, enabled: true, want: true},
+		{name: "pass-through keeps disabled", hooks: []EvaluationHook{passThrough}, enabled: false, want: false},
+		{name: "override enables", hooks: []EvaluationHook{forceOn}, enabled: false, want: true},
+		{name: "override disables", hooks: []EvaluationHook{forceOff}, enabled: true, want: false},
+		{name: "later hooks see earlier overrides", hooks: []EvaluationHook{forceOn, passThrough}, enabled: false, want: true},
+		{name: "last override wins", hooks: []EvaluationHook{forceOn, forceOff}, enabled: false, want: false},
+		{name: "error keeps result", hooks: []EvaluationHook{failing}, enabled: false, want: false},
+		{name: "panic keeps result", hooks: []EvaluationHook{panicking}, enabled: false, want: false},
+		{name: "hooks after a panic still run", hooks: []EvaluationHook{panicking, forceOn}, enabled: false, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withHooks(t, tt.hooks...)
+			if got := runHooks(context.Background(), Evaluation{Feature: "new-ui", Enabled: tt.enabled}); got != tt.want {
+				t.Errorf("runHooks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForceEnabledHook(t *testing.T) {
+	hook := NewForceEnabledHook([]string{"Z123456"})
+
+	tests := []struct {
+		name   string
+		userID string
+		want   bool
+	}{
+		{name: "listed user", userID: "Z123456", want: true},
+		{name: "other user", userID: "Z999999", want: false},
+		{name: "no user", userID: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := Evaluation{Feature: "new-ui", Context: unleashcontext.Context{UserId: tt.userID}}
+			enabled, err := hook.AfterEvaluate(context.Background(), evaluation)
+			if err != nil {
+				t.Fatalf("AfterEvaluate: %v", err)
+			}
+			if enabled != tt.want {
+				t.Errorf("AfterEvaluate = %v, want %v", enabled, tt.want)
+			}
+		})
+	}
+}
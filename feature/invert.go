@@ -0,0 +1,15 @@
+package feature
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// shouldInvert reports whether the result for the feature should be negated before it is returned,
+// either because the caller asked for it with ?invert=true or because the feature is listed in INVERTED_FEATURES.
+// This lets a "disable X" toggle be consumed as "enable not-X" without negation logic in every caller.
+func shouldInvert(r *http.Request, featureName string) bool {
+	return r.URL.Query().Get("invert") == "true" || slices.Contains(env.InvertedFeatures, featureName)
+}
@@ -0,0 +1,82 @@
+package feature
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// killSwitchTTL is how long a kill switch evaluation is cached per app.
+var killSwitchTTL = 5 * time.Second
+
+// killSwitchDefault is the result returned for every feature while the kill switch is active.
+// KILL_SWITCH_DEFAULT is checked by env.Validate at startup.
+var killSwitchDefault, _ = strconv.ParseBool(env.KillSwitchDefault)
+
+// killSwitchFeatureLabel is the feature metric label of checks answered by the kill switch.
+// Those features are not evaluated, so it is not known whether they exist, see featureLabel.
+const killSwitchFeatureLabel = "kill_switch"
+
+type killSwitchState struct {
+	active    bool
+	checkedAt time.Time
+}
+
+// appKillSwitch is the cached kill switch state of an app.
+type appKillSwitch struct {
+	state atomic.Pointer[killSwitchState]
+	// refreshing is set while a request re-evaluates an expired state, so the app's other requests don't wait for it
+	refreshing atomic.Bool
+}
+
+// killSwitches maps app names to their *appKillSwitch.
+var killSwitches sync.Map
+
+// killSwitchActive reports whether the kill switch feature is disabled for the app, meaning the proxy
+// should return killSwitchDefault instead of evaluating features. The result is cached per app for killSwitchTTL.
+// Only an existing kill switch feature that evaluates to false activates it, so a missing feature never disables the proxy.
+// Always false unless KILL_SWITCH_FEATURE is set.
+func killSwitchActive(appName string, client clients.Evaluator) bool {
+	if env.KillSwitchFeature == "" {
+		return false
+	}
+
+	value, _ := killSwitches.LoadOrStore(appName, new(appKillSwitch))
+	killSwitch := value.(*appKillSwitch)
+
+	state := killSwitch.state.Load()
+	if state != nil && time.Since(state.checkedAt) < killSwitchTTL {
+		return state.active
+	}
+
+	// One request refreshes an expired state while the others keep using it
+	if !killSwitch.refreshing.CompareAndSwap(false, true) {
+		if state != nil {
+			return state.active
+		}
+		return evaluateKillSwitch(appName, client).active
+	}
+	defer killSwitch.refreshing.Store(false)
+
+	state = evaluateKillSwitch(appName, client)
+	killSwitch.state.Store(state)
+	return state.active
+}
+
+// evaluateKillSwitch evaluates the kill switch feature for the app.
+func evaluateKillSwitch(appName string, client clients.Evaluator) *killSwitchState {
+	enabled, known := client.Evaluate(env.KillSwitchFeature, unleashcontext.Context{
+		Environment: clients.Environment(appName),
+		AppName:     appName,
+	})
+
+	return &killSwitchState{
+		active:    known && !enabled,
+		checkedAt: time.Now(),
+	}
+}
@@ -0,0 +1,91 @@
+package feature
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testKillSwitch = "proxy-enabled"
+
+// withKillSwitch enables the kill switch feature with the given TTL for the duration of the test.
+func withKillSwitch(t *testing.T, ttl time.Duration) {
+	t.Helper()
+	previousFeature, previousTTL := env.KillSwitchFeature, killSwitchTTL
+	env.KillSwitchFeature, killSwitchTTL = testKillSwitch, ttl
+	killSwitches.Clear()
+	t.Cleanup(func() {
+		env.KillSwitchFeature, killSwitchTTL = previousFeature, previousTTL
+		killSwitches.Clear()
+	})
+}
+
+func TestKillSwitchActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		features map[string]bool
+		want     bool
+	}{
+		{name: "kill switch enabled", features: map[string]bool{testKillSwitch: true}, want: false},
+		{name: "kill switch disabled", features: map[string]bool{testKillSwitch: false}, want: true},
+		{name: "kill switch unknown", features: map[string]bool{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withKillSwitch(t, time.Minute)
+			evaluator := withEvaluator(t, tt.features)
+
+			if got := killSwitchActive(testApp, evaluator); got != tt.want {
+				t.Errorf("killSwitchActive = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKillSwitchIsCachedForTTL(t *testing.T) {
+	withKillSwitch(t, 50*time.Millisecond)
+	evaluator := withEvaluator(t, map[string]bool{testKillSwitch: true})
+
+	if killSwitchActive(testApp, evaluator) {
+		t.Fatal("kill switch active while its feature is enabled")
+	}
+
+	// Within the TTL the cached state is used
+	evaluator.Features = map[string]bool{testKillSwitch: false}
+	if killSwitchActive(testApp, evaluator) {
+		t.Error("kill switch re-evaluated within its TTL")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !killSwitchActive(testApp, evaluator) {
+		t.Error("kill switch not re-evaluated after its TTL")
+	}
+}
+
+func TestHandlerWithActiveKillSwitch(t *testing.T) {
+	withKillSwitch(t, time.Minute)
+	previousDefault := killSwitchDefault
+	killSwitchDefault = true
+	t.Cleanup(func() { killSwitchDefault = previousDefault })
+	withEvaluator(t, map[string]bool{testKillSwitch: false, "new-ui": false})
+
+	requests := metrics.FeatureRequestsTotal.WithLabelValues(killSwitchFeatureLabel, testApp, "true")
+	before := testutil.ToFloat64(requests)
+
+	w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"enabled":true}` {
+		t.Errorf("body = %s, want the kill switch default", body)
+	}
+	if got := testutil.ToFloat64(requests) - before; got != 1 {
+		t.Errorf("feature_requests_total{feature=%q} increased by %v, want 1", killSwitchFeatureLabel, got)
+	}
+}
@@ -0,0 +1,55 @@
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// defaultAppNavIdents are the navIdents used per app for requests without one, from DEFAULT_APP_NAV_IDENTS.
+// DEFAULT_APP_NAV_IDENTS is checked by env.Validate at startup.
+var defaultAppNavIdents, _ = parseDefaultAppNavIdents(env.DefaultAppNavIdents)
+
+// parseDefaultAppNavIdents parses DEFAULT_APP_NAV_IDENTS, a JSON object of app names to navIdents.
+func parseDefaultAppNavIdents(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var navIdents map[string]string
+	if err := json.Unmarshal([]byte(value), &navIdents); err != nil {
+		return nil, fmt.Errorf("failed to parse DEFAULT_APP_NAV_IDENTS: %w", err)
+	}
+
+	return navIdents, nil
+}
+
+// defaultNavIdent returns the navIdent to use for the app's requests without one, e.g. a service principal
+// for backend-to-backend checks. Falls back to DEFAULT_NAV_IDENT, and returns an empty string if neither is set.
+func defaultNavIdent(appName string) string {
+	if navIdent, ok := defaultAppNavIdents[appName]; ok && navIdent != "" {
+		return navIdent
+	}
+	return env.DefaultNavIdent
+}
+
+// applyDefaultNavIdent sets the app's default navIdent on a request without one,
+// so service traffic evaluates userId-based strategies deterministically.
+func applyDefaultNavIdent(log *slog.Logger, req *Request) {
+	if req.NavIdent != "" {
+		return
+	}
+
+	navIdent := defaultNavIdent(req.AppName)
+	if navIdent == "" {
+		return
+	}
+
+	req.NavIdent = navIdent
+	log.Debug("Applied default navIdent "+navIdent+" for "+req.AppName,
+		"app_name", req.AppName,
+		"user_id", navIdent,
+	)
+}
@@ -0,0 +1,40 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestDefaultNavIdent(t *testing.T) {
+	appNavIdents, err := parseDefaultAppNavIdents(`{"kabal-api":"srvkabal","kabal-frontend":""}`)
+	if err != nil {
+		t.Fatalf("parseDefaultAppNavIdents: %v", err)
+	}
+	previousApps, previousDefault := defaultAppNavIdents, env.DefaultNavIdent
+	defaultAppNavIdents, env.DefaultNavIdent = appNavIdents, "srvdefault"
+	t.Cleanup(func() { defaultAppNavIdents, env.DefaultNavIdent = previousApps, previousDefault })
+
+	tests := []struct {
+		app  string
+		want string
+	}{
+		{app: "kabal-api", want: "srvkabal"},
+		{app: "kabal-frontend", want: "srvdefault"},
+		{app: "other-app", want: "srvdefault"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			if got := defaultNavIdent(tt.app); got != tt.want {
+				t.Errorf("defaultNavIdent(%q) = %q, want %q", tt.app, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDefaultAppNavIdentsRejectsMalformedJSON(t *testing.T) {
+	if _, err := parseDefaultAppNavIdents(`["srvkabal"]`); err == nil {
+		t.Error("parseDefaultAppNavIdents accepted a JSON array")
+	}
+}
@@ -0,0 +1,41 @@
+package feature
+
+import (
+	"strings"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// nameNormalizer replaces aliased separators in feature names with the canonical separator.
+// It is nil when normalization is disabled.
+var nameNormalizer = newNameNormalizer(env.FeatureNameSeparator, env.FeatureNameAliasSeparators)
+
+// newNameNormalizer creates a replacer mapping each aliased separator character to the canonical separator.
+// Returns nil if no canonical separator or aliases are configured.
+func newNameNormalizer(canonical, aliases string) *strings.Replacer {
+	if canonical == "" || aliases == "" {
+		return nil
+	}
+
+	var oldnew []string
+	for _, alias := range aliases {
+		if string(alias) != canonical {
+			oldnew = append(oldnew, string(alias), canonical)
+		}
+	}
+
+	if len(oldnew) == 0 {
+		return nil
+	}
+
+	return strings.NewReplacer(oldnew...)
+}
+
+// NormalizeName maps aliased separators in a valid feature name to the canonical separator.
+// The name is returned unchanged if normalization is disabled.
+func NormalizeName(name string) string {
+	if nameNormalizer == nil {
+		return name
+	}
+	return nameNormalizer.Replace(name)
+}
@@ -0,0 +1,94 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/logging"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+)
+
+// OverrideHook forces specific features on or off for specific users, e.g. QA users testing a gated feature in production.
+// Forced-off takes precedence if a user is listed in both.
+type OverrideHook struct {
+	forcedOn  map[string][]string
+	forcedOff map[string][]string
+}
+
+// NewOverrideHook creates an OverrideHook from maps of feature name to navIdents.
+func NewOverrideHook(forcedOn, forcedOff map[string][]string) *OverrideHook {
+	return &OverrideHook{
+		forcedOn:  forcedOn,
+		forcedOff: forcedOff,
+	}
+}
+
+// OverrideHookFromEnv creates an OverrideHook from FEATURE_FORCED_ON and FEATURE_FORCED_OFF.
+// Returns nil if neither is set, and an error if either is not a valid JSON object of feature name to navIdents.
+func OverrideHookFromEnv() (*OverrideHook, error) {
+	forcedOn, err := parseOverrides("FEATURE_FORCED_ON", env.FeatureForcedOn)
+	if err != nil {
+		return nil, err
+	}
+	forcedOff, err := parseOverrides("FEATURE_FORCED_OFF", env.FeatureForcedOff)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(forcedOn) == 0 && len(forcedOff) == 0 {
+		return nil, nil
+	}
+
+	return NewOverrideHook(forcedOn, forcedOff), nil
+}
+
+func parseOverrides(name, value string) (map[string][]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var overrides map[string][]string
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return overrides, nil
+}
+
+// Name returns the name of the hook
+func (h *OverrideHook) Name() string {
+	return "feature_override"
+}
+
+// AfterEvaluate forces the feature on or off if the user is listed for it
+func (h *OverrideHook) AfterEvaluate(ctx context.Context, evaluation Evaluation) (bool, error) {
+	navIdent := evaluation.Context.UserId
+	if navIdent == "" {
+		return evaluation.Enabled, nil
+	}
+
+	if slices.Contains(h.forcedOff[evaluation.Feature], navIdent) {
+		h.apply(ctx, evaluation, false)
+		return false, nil
+	}
+
+	if slices.Contains(h.forcedOn[evaluation.Feature], navIdent) {
+		h.apply(ctx, evaluation, true)
+		return true, nil
+	}
+
+	return evaluation.Enabled, nil
+}
+
+// apply logs and records an applied override.
+func (h *OverrideHook) apply(ctx context.Context, evaluation Evaluation, enabled bool) {
+	logging.FromContext(ctx).Info(fmt.Sprintf("Forced %s = %t for test user", evaluation.Feature, enabled),
+		"feature", evaluation.Feature,
+		"enabled", enabled,
+		"evaluated", evaluation.Enabled,
+	)
+	metrics.RecordFeatureOverride(featureLabel(evaluation.Feature, evaluation.Known), enabled)
+}
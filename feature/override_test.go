@@ -0,0 +1,93 @@
+package feature
+
+import (
+	"context"
+	"testing"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOverrideHookFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		forcedOn  string
+		forcedOff string
+		wantHook  bool
+		wantErr   bool
+	}{
+		{name: "unset"},
+		{name: "forced on", forcedOn: `{"new-ui":["Z123456"]}`, wantHook: true},
+		{name: "forced off", forcedOff: `{"new-ui":["Z123456"]}`, wantHook: true},
+		{name: "malformed forced on", forcedOn: `{"new-ui":`, wantErr: true},
+		{name: "malformed forced off", forcedOff: `{"new-ui":"Z123456"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousOn, previousOff := env.FeatureForcedOn, env.FeatureForcedOff
+			env.FeatureForcedOn, env.FeatureForcedOff = tt.forcedOn, tt.forcedOff
+			t.Cleanup(func() { env.FeatureForcedOn, env.FeatureForcedOff = previousOn, previousOff })
+
+			hook, err := OverrideHookFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OverrideHookFromEnv error = %v, want error %v", err, tt.wantErr)
+			}
+			if (hook != nil) != tt.wantHook {
+				t.Errorf("OverrideHookFromEnv hook = %v, want hook %v", hook, tt.wantHook)
+			}
+		})
+	}
+}
+
+func TestOverrideHook(t *testing.T) {
+	hook := NewOverrideHook(
+		map[string][]string{"new-ui": {"Z111111", "Z333333"}},
+		map[string][]string{"new-ui": {"Z222222", "Z333333"}},
+	)
+
+	tests := []struct {
+		name    string
+		userID  string
+		feature string
+		enabled bool
+		want    bool
+	}{
+		{name: "forced on", userID: "Z111111", feature: "new-ui", enabled: false, want: true},
+		{name: "forced off", userID: "Z222222", feature: "new-ui", enabled: true, want: false},
+		{name: "forced off takes precedence", userID: "Z333333", feature: "new-ui", enabled: true, want: false},
+		{name: "other user", userID: "Z999999", feature: "new-ui", enabled: true, want: true},
+		{name: "other feature", userID: "Z111111", feature: "dark-mode", enabled: false, want: false},
+		{name: "no user", feature: "new-ui", enabled: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := Evaluation{Feature: tt.feature, Context: unleashcontext.Context{UserId: tt.userID}, Enabled: tt.enabled}
+			got, err := hook.AfterEvaluate(context.Background(), evaluation)
+			if err != nil {
+				t.Fatalf("AfterEvaluate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AfterEvaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideHookLabelsUnknownFeatures(t *testing.T) {
+	hook := NewOverrideHook(map[string][]string{"retired-ui": {"Z111111"}}, nil)
+	overrides := metrics.FeatureOverridesTotal.WithLabelValues(unknownFeatureLabel, "true")
+	before := testutil.ToFloat64(overrides)
+
+	evaluation := Evaluation{Feature: "retired-ui", Context: unleashcontext.Context{UserId: "Z111111"}}
+	if got, err := hook.AfterEvaluate(context.Background(), evaluation); err != nil || !got {
+		t.Fatalf("AfterEvaluate = %v, %v, want forced on", got, err)
+	}
+
+	if got := testutil.ToFloat64(overrides) - before; got != 1 {
+		t.Errorf("feature_overrides_total{feature=%q} increased by %v, want 1 for a feature unknown to Unleash", unknownFeatureLabel, got)
+	}
+}
@@ -0,0 +1,46 @@
+package feature
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// allowedMethods is the Allow header for the feature check endpoint.
+const allowedMethods = "GET, POST, QUERY"
+
+// setQueryHeaders sets the headers of a successful QUERY response. Per the HTTP QUERY method draft,
+// the method is safe, so unlike POST its responses may be cached, keyed on the request body.
+// They are cached privately for FEATURE_CACHE_TTL at most, the staleness the proxy itself accepts,
+// and must be revalidated if the response cache is disabled.
+// Content-Location points to the equivalent GET request, if the query can be expressed in query parameters.
+// It carries over ?invert and ?meta, so the GET returns the same representation.
+func setQueryHeaders(w http.ResponseWriter, r *http.Request, featureName string, req Request) {
+	maxAge := int(math.Ceil(cacheTTL.Seconds()))
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+
+	if req.UnleashContext != nil || len(req.Properties) > 0 {
+		return
+	}
+
+	query := url.Values{}
+	query.Set("appName", req.AppName)
+	for name, value := range map[string]string{
+		"navIdent":    req.NavIdent,
+		"podName":     req.PodName,
+		"currentTime": req.CurrentTime,
+		"seed":        req.Seed,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	for _, name := range []string{"invert", "meta"} {
+		if r.URL.Query().Get(name) == "true" {
+			query.Set(name, "true")
+		}
+	}
+
+	w.Header().Set("Content-Location", PathPrefix+url.PathEscape(featureName)+"?"+query.Encode())
+}
@@ -0,0 +1,42 @@
+package feature
+
+import (
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+)
+
+// evaluationRetryDelay is how long to wait before evaluating an unknown feature once more.
+// A feature can briefly appear unknown while the SDK swaps in a refreshed repository. Zero disables the retry.
+var evaluationRetryDelay time.Duration
+
+// unknownFeatureLabel is the feature metric label of features that do not exist in Unleash.
+// Callers can send any valid feature name, so labelling those by name would create unbounded series.
+const unknownFeatureLabel = "unknown"
+
+// featureLabel returns the feature metric label of a feature: its name if it is known to Unleash,
+// and unknownFeatureLabel otherwise.
+func featureLabel(featureName string, known bool) string {
+	if !known {
+		return unknownFeatureLabel
+	}
+	return featureName
+}
+
+// evaluate returns whether the feature is enabled and whether it is known to the SDK,
+// retrying once after evaluationRetryDelay if the feature was not known.
+func evaluate(client clients.Evaluator, featureName string, unleashCtx unleashcontext.Context) (enabled bool, known bool) {
+	enabled, known = client.Evaluate(featureName, unleashCtx)
+	if known || evaluationRetryDelay <= 0 {
+		return enabled, known
+	}
+
+	time.Sleep(evaluationRetryDelay)
+
+	enabled, known = client.Evaluate(featureName, unleashCtx)
+	metrics.RecordEvaluationRetry(featureLabel(featureName, known), known)
+
+	return enabled, known
+}
@@ -0,0 +1,39 @@
+package feature
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// sessionCookieMaxAge keeps anonymous sessions stable across visits, in seconds (one year).
+const sessionCookieMaxAge = 365 * 24 * 60 * 60
+
+// anonymousSessionID returns the anonymous session ID from the ANONYMOUS_SESSION_COOKIE cookie,
+// setting the cookie with a new random ID if the request has none. Using it as the Unleash SessionId
+// keeps a logged-out user consistently in or out of a gradual rollout.
+// Returns an empty string unless ANONYMOUS_SESSION_COOKIE is set. Must be called before the response is written.
+func anonymousSessionID(w http.ResponseWriter, r *http.Request) string {
+	if env.AnonymousSessionCookie == "" {
+		return ""
+	}
+
+	if cookie, err := r.Cookie(env.AnonymousSessionCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	sessionID := rand.Text()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     env.AnonymousSessionCookie,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   sessionCookieMaxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return sessionID
+}
@@ -0,0 +1,56 @@
+package feature
+
+import (
+	"log/slog"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+)
+
+// shadowSlots bounds the number of shadow evaluations running at once, to SHADOW_CONCURRENCY.
+// Shadow evaluations only feed a metric, so under load they are dropped rather than queued.
+var shadowSlots = make(chan struct{}, 16)
+
+// shadowEvaluate evaluates the feature again in the background with the app's shadow client, which fetches
+// the toggles of the shadow environment, and records a mismatch if the result differs from the primary result.
+// It never blocks or affects the response, and is skipped if SHADOW_CONCURRENCY evaluations are already running.
+// Disabled unless SHADOW_ENVIRONMENT is set, and skipped until the app's shadow client is ready.
+func shadowEvaluate(appName, featureName string, unleashCtx unleashcontext.Context, primary bool, primaryKnown bool) {
+	if env.ShadowEnvironment == "" {
+		return
+	}
+
+	shadow, ok := clients.GetShadow(appName)
+	if !ok {
+		return
+	}
+
+	select {
+	case shadowSlots <- struct{}{}:
+	default:
+		metrics.RecordShadowDropped()
+		return
+	}
+
+	shadowCtx := unleashCtx
+	shadowCtx.Environment = env.ShadowEnvironment
+
+	go func() {
+		defer func() { <-shadowSlots }()
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("Shadow evaluation panicked",
+					slog.String("feature", featureName),
+					slog.Any("panic", r),
+				)
+			}
+		}()
+
+		enabled, known := shadow.Evaluate(featureName, shadowCtx)
+		if enabled != primary {
+			metrics.RecordShadowMismatch(featureLabel(featureName, primaryKnown || known))
+		}
+	}()
+}
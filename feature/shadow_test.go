@@ -0,0 +1,95 @@
+package feature
+
+import (
+	"testing"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// withShadow evaluates shadows for testApp with a fake Evaluator with the given features for the duration of the test.
+func withShadow(t *testing.T, features map[string]bool) *clientstest.Evaluator {
+	t.Helper()
+	previous := env.ShadowEnvironment
+	env.ShadowEnvironment = "shadow"
+	evaluator := clientstest.NewEvaluator(features)
+	clients.RegisterShadowEvaluator(testApp, evaluator)
+	t.Cleanup(func() {
+		env.ShadowEnvironment = previous
+		clients.UnregisterShadowEvaluator(testApp)
+	})
+	return evaluator
+}
+
+// waitForShadows waits until no shadow evaluations are running.
+func waitForShadows(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(shadowSlots) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("shadow evaluations still running after 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShadowEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		shadow       map[string]bool
+		primary      bool
+		wantMismatch float64
+	}{
+		{name: "match", shadow: map[string]bool{"new-ui": true}, primary: true, wantMismatch: 0},
+		{name: "mismatch", shadow: map[string]bool{"new-ui": false}, primary: true, wantMismatch: 1},
+		{name: "unknown in shadow", shadow: map[string]bool{}, primary: true, wantMismatch: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadow := withShadow(t, tt.shadow)
+			mismatches := metrics.FeatureShadowMismatches.WithLabelValues("new-ui")
+			before := testutil.ToFloat64(mismatches)
+
+			shadowEvaluate(testApp, "new-ui", unleashcontext.Context{AppName: testApp, Environment: "development"}, tt.primary, true)
+			waitForShadows(t)
+
+			contexts := shadow.Contexts()
+			if len(contexts) != 1 {
+				t.Fatalf("shadow evaluations = %d, want 1", len(contexts))
+			}
+			if contexts[0].Environment != "shadow" {
+				t.Errorf("shadow environment = %q, want %q", contexts[0].Environment, "shadow")
+			}
+			if got := testutil.ToFloat64(mismatches) - before; got != tt.wantMismatch {
+				t.Errorf("feature_shadow_mismatch_total increased by %v, want %v", got, tt.wantMismatch)
+			}
+		})
+	}
+}
+
+func TestShadowEvaluateDropsWhenBusy(t *testing.T) {
+	shadow := withShadow(t, map[string]bool{"new-ui": true})
+	before := testutil.ToFloat64(metrics.FeatureShadowDropped)
+
+	// Occupy every slot, as if SHADOW_CONCURRENCY evaluations were running
+	for range cap(shadowSlots) {
+		shadowSlots <- struct{}{}
+	}
+	shadowEvaluate(testApp, "new-ui", unleashcontext.Context{AppName: testApp}, true, true)
+	for range cap(shadowSlots) {
+		<-shadowSlots
+	}
+
+	if got := shadow.Evaluations(); got != 0 {
+		t.Errorf("shadow evaluations = %d, want the evaluation dropped", got)
+	}
+	if got := testutil.ToFloat64(metrics.FeatureShadowDropped) - before; got != 1 {
+		t.Errorf("feature_shadow_dropped_total increased by %v, want 1", got)
+	}
+}
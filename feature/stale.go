@@ -0,0 +1,53 @@
+package feature
+
+import (
+	"container/list"
+	"context"
+	"time"
+
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+)
+
+// staleResultsAfter is how long a client may go without successful contact with Unleash before its feature data
+// counts as stale, and requests are answered with their last-known result instead. Zero disables last-known results.
+var staleResultsAfter time.Duration
+
+// lastKnownResults holds the last result of each keyable request evaluated while its client was fresh.
+// The entries do not expire, but the least recently used are evicted once the cache is full.
+var lastKnownResults = &resultCache{
+	entries: make(map[cacheKey]*list.Element),
+	order:   list.New(),
+}
+
+// clientStale reports whether the client's feature data is stale.
+// Only Evaluators that implement clients.Freshness can be stale.
+func clientStale(client clients.Evaluator) bool {
+	freshness, ok := client.(clients.Freshness)
+	return ok && time.Since(freshness.LastSuccess()) > staleResultsAfter
+}
+
+// evaluateLastKnown evaluates a feature like evaluateCached, remembering the result while the client is fresh.
+// Once the client is stale, the last-known result of an identical request is returned instead, and reported as stale.
+// Without one, the client evaluates its last fetched feature data, and the result is neither remembered nor stale.
+func evaluateLastKnown(ctx context.Context, client clients.Evaluator, featureName string, unleashCtx unleashcontext.Context, req Request) (enabled bool, known bool, cacheHit bool, stale bool) {
+	if staleResultsAfter <= 0 || !keyable(req, unleashCtx) {
+		enabled, known, cacheHit = evaluateCached(ctx, client, featureName, unleashCtx, req)
+		return enabled, known, cacheHit, false
+	}
+
+	key := newCacheKey(featureName, unleashCtx)
+	if clientStale(client) {
+		if entry, ok := lastKnownResults.get(key, time.Now()); ok {
+			return entry.enabled, entry.known, false, true
+		}
+		enabled, known, cacheHit = evaluateCached(ctx, client, featureName, unleashCtx, req)
+		return enabled, known, cacheHit, false
+	}
+
+	enabled, known, cacheHit = evaluateCached(ctx, client, featureName, unleashCtx, req)
+	if ctx.Err() == nil {
+		lastKnownResults.put(cacheEntry{key: key, enabled: enabled, known: known})
+	}
+	return enabled, known, cacheHit, false
+}
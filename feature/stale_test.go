@@ -0,0 +1,60 @@
+package feature
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+)
+
+func TestHandlerServesLastKnownResultsWhileStale(t *testing.T) {
+	clientstest.SetForTest(t, &staleResultsAfter, time.Minute)
+	evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+	t.Cleanup(func() { lastKnownResults.removeApp(testApp) })
+
+	check := func(navIdent, query string) Response {
+		t.Helper()
+		body := `{"appName":"` + testApp + `","navIdent":"` + navIdent + `"}`
+		return decodeResponse(t, checkFeature(PathPrefix+"new-ui"+query, strings.NewReader(body)))
+	}
+
+	if got := check("Z123456", "?meta=true"); !got.Enabled || got.Stale {
+		t.Fatalf("fresh client: %+v, want enabled and not stale", got)
+	}
+
+	// Refreshing fails, and the client's feature data no longer matches what Unleash had
+	evaluator.Features["new-ui"] = false
+	evaluator.SetLastSuccess(time.Now().Add(-time.Hour))
+
+	if got := check("Z123456", "?meta=true"); !got.Enabled || !got.Stale {
+		t.Errorf("stale client: %+v, want the last-known enabled result marked stale", got)
+	}
+	w := checkFeature(PathPrefix+"new-ui", strings.NewReader(`{"appName":"`+testApp+`","navIdent":"Z123456"}`))
+	if body := w.Body.String(); strings.Contains(body, "stale") {
+		t.Errorf("response without ?meta=true %s, want no stale flag", body)
+	}
+	if got := check("Z654321", "?meta=true"); got.Enabled || got.Stale {
+		t.Errorf("stale client without a last-known result: %+v, want the client's evaluation, not stale", got)
+	}
+
+	// Once the client is fresh again, it is evaluated as usual
+	evaluator.SetLastSuccess(time.Now())
+	if got := check("Z123456", "?meta=true"); got.Enabled || got.Stale {
+		t.Errorf("fresh client again: %+v, want disabled and not stale", got)
+	}
+}
+
+func TestHandlerIgnoresStalenessWhenDisabled(t *testing.T) {
+	clientstest.SetForTest(t, &staleResultsAfter, 0)
+	evaluator := withEvaluator(t, map[string]bool{"new-ui": true})
+	body := `{"appName":"` + testApp + `","navIdent":"Z123456"}`
+
+	decodeResponse(t, checkFeature(PathPrefix+"new-ui", strings.NewReader(body)))
+	evaluator.Features["new-ui"] = false
+	evaluator.SetLastSuccess(time.Now().Add(-time.Hour))
+
+	if got := decodeResponse(t, checkFeature(PathPrefix+"new-ui?meta=true", strings.NewReader(body))); got.Enabled || got.Stale {
+		t.Errorf("%+v, want the client's evaluation without STALE_RESULTS_AFTER", got)
+	}
+}
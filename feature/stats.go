@@ -0,0 +1,128 @@
+package feature
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statsSlots is the number of slots the stats window is divided into.
+// Counts expire one slot at a time as the window rolls forward.
+const statsSlots = 10
+
+// statsWindow is the time window evaluation results are aggregated over.
+var statsWindow = 5 * time.Minute
+
+// statsMaxFeatures bounds the number of features tracked per slot, to bound memory.
+var statsMaxFeatures = 1000
+
+type statsCounts struct {
+	Enabled  int64 `json:"enabled"`
+	Disabled int64 `json:"disabled"`
+}
+
+type statsSlot struct {
+	start  time.Time
+	counts map[string]*statsCounts
+}
+
+var (
+	slots   [statsSlots]statsSlot
+	slotsMu sync.Mutex
+)
+
+// recordStats adds an evaluation result to the rolling stats.
+// Stats are disabled if the window is too short to divide into slots, e.g. zero.
+func recordStats(feature string, enabled bool) {
+	slotDuration := statsWindow / statsSlots
+	if slotDuration <= 0 {
+		return
+	}
+
+	now := time.Now()
+	start := now.Truncate(slotDuration)
+	slot := &slots[(now.UnixNano()/int64(slotDuration))%statsSlots]
+
+	slotsMu.Lock()
+	defer slotsMu.Unlock()
+
+	if !slot.start.Equal(start) {
+		slot.start = start
+		slot.counts = make(map[string]*statsCounts)
+	}
+
+	counts, ok := slot.counts[feature]
+	if !ok {
+		if len(slot.counts) >= statsMaxFeatures {
+			return
+		}
+		counts = &statsCounts{}
+		slot.counts[feature] = counts
+	}
+
+	if enabled {
+		counts.Enabled++
+	} else {
+		counts.Disabled++
+	}
+}
+
+// FeatureStats is the aggregated evaluation results of a feature within the stats window.
+type FeatureStats struct {
+	statsCounts
+	// Ratio is the fraction of evaluations that were enabled.
+	Ratio float64 `json:"ratio"`
+}
+
+// StatsResponse represents the JSON response for the stats endpoint.
+type StatsResponse struct {
+	Window   string                  `json:"window"`
+	Features map[string]FeatureStats `json:"features"`
+}
+
+// Stats returns the aggregated evaluation results per feature within the stats window.
+func Stats() map[string]FeatureStats {
+	cutoff := time.Now().Add(-statsWindow)
+	totals := make(map[string]statsCounts)
+
+	slotsMu.Lock()
+	for _, slot := range slots {
+		if slot.start.Before(cutoff) {
+			continue
+		}
+		for feature, counts := range slot.counts {
+			total := totals[feature]
+			total.Enabled += counts.Enabled
+			total.Disabled += counts.Disabled
+			totals[feature] = total
+		}
+	}
+	slotsMu.Unlock()
+
+	stats := make(map[string]FeatureStats, len(totals))
+	for feature, total := range totals {
+		stats[feature] = FeatureStats{
+			statsCounts: total,
+			Ratio:       float64(total.Enabled) / float64(total.Enabled+total.Disabled),
+		}
+	}
+
+	return stats
+}
+
+// StatsHandler handles GET /admin/stats, returning the enabled ratio per feature as actually evaluated.
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(StatsResponse{
+		Window:   statsWindow.String(),
+		Features: Stats(),
+	})
+}
@@ -0,0 +1,71 @@
+package feature
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// resetStats clears the rolling stats now and when the test is done.
+func resetStats(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		slotsMu.Lock()
+		defer slotsMu.Unlock()
+		slots = [statsSlots]statsSlot{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestStatsHandler(t *testing.T) {
+	resetStats(t)
+	withEvaluator(t, map[string]bool{"stats-on": true, "stats-off": false})
+
+	for _, name := range []string{"stats-on", "stats-on", "stats-off"} {
+		if w := checkFeature(PathPrefix+name, strings.NewReader(`{"appName":"`+testApp+`"}`)); w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, body %s", name, w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	StatsHandler(w, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response StatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Window != statsWindow.String() {
+		t.Errorf("window = %q, want %q", response.Window, statsWindow)
+	}
+	if got := response.Features["stats-on"]; got.Enabled != 2 || got.Disabled != 0 || got.Ratio != 1 {
+		t.Errorf("stats-on = %+v, want 2 enabled", got)
+	}
+	if got := response.Features["stats-off"]; got.Enabled != 0 || got.Disabled != 1 || got.Ratio != 0 {
+		t.Errorf("stats-off = %+v, want 1 disabled", got)
+	}
+}
+
+func TestStatsHandlerRejectsOtherMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	StatsHandler(w, httptest.NewRequest(http.MethodPost, "/admin/stats", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if got := w.Header().Get("Allow"); got != http.MethodGet {
+		t.Errorf("Allow = %q, want GET", got)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "method_not_allowed" {
+		t.Errorf("code %q, want method_not_allowed", response.Code)
+	}
+}
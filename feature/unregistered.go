@@ -0,0 +1,53 @@
+package feature
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+)
+
+// maxUnregisteredApps bounds how many distinct unregistered apps are served in permissive mode.
+// App names are used as metric labels, so further unregistered apps are rejected as in strict mode.
+const maxUnregisteredApps = 100
+
+var (
+	unregisteredApps   = make(map[string]struct{})
+	unregisteredAppsMu sync.Mutex
+)
+
+// unregisteredClient returns the default client for an app that is not an inbound application,
+// if APP_ENFORCEMENT is permissive. The first request from each unregistered app is logged.
+// Inbound applications whose client is not ready are not unregistered, and get no client.
+func unregisteredClient(log *slog.Logger, appName string) (clients.Evaluator, bool) {
+	if clients.IsValidApp(appName) {
+		return nil, false
+	}
+
+	client, ok := clients.Default()
+	if !ok {
+		return nil, false
+	}
+
+	unregisteredAppsMu.Lock()
+	_, seen := unregisteredApps[appName]
+	if !seen {
+		if len(unregisteredApps) >= maxUnregisteredApps {
+			unregisteredAppsMu.Unlock()
+			return nil, false
+		}
+		unregisteredApps[appName] = struct{}{}
+	}
+	unregisteredAppsMu.Unlock()
+
+	if !seen {
+		log.Warn("Unregistered app_name: "+appName+", evaluating with the default client",
+			"app_name", appName,
+		)
+	}
+
+	metrics.RecordUnregisteredAppRequest(appName)
+
+	return client, true
+}
@@ -0,0 +1,144 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Unleash/unleash-go-sdk/v5/api"
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var VariantPath = PathPrefix + "{name}/variant"
+
+// VariantResponse represents the JSON response for variant requests.
+type VariantResponse struct {
+	Name    string         `json:"name"`
+	Enabled bool           `json:"enabled"`
+	Payload VariantPayload `json:"payload"`
+}
+
+// VariantPayload is the payload of a resolved variant.
+type VariantPayload struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newVariantResponse(variant *api.Variant) VariantResponse {
+	return VariantResponse{
+		Name:    variant.Name,
+		Enabled: variant.Enabled,
+		Payload: VariantPayload{
+			Type:  variant.Payload.Type,
+			Value: variant.Payload.Value,
+		},
+	}
+}
+
+// VariantHandler handles variant requests.
+// It expects requests to POST or QUERY /features/{featureName}/variant with the same JSON body as Handler,
+// and applies the same FEATURE_REQUEST_TIMEOUT, kill switch, hooks and REQUIRE_KNOWN_FEATURE.
+// Variant checks are counted in feature_requests_total, but the response cache, stats, flip tracking and
+// decision export only apply to boolean feature checks, since they are defined in terms of an enabled result.
+func VariantHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	ctx, span, log, end := startCheck(w, r, "featureVariantHandler")
+	defer end()
+
+	if !allowMethod(w, r, span, log, "POST, QUERY") {
+		return
+	}
+
+	featureName, ok := checkFeatureName(w, r, span, log, r.PathValue("name"))
+	if !ok {
+		return
+	}
+
+	req, client, ok := decodeRequest(w, r, span, log, featureName)
+	if !ok {
+		return
+	}
+
+	// The kill switch takes precedence over all evaluation and resolves to the disabled variant
+	var variant *api.Variant
+	label := killSwitchFeatureLabel
+	if killSwitchActive(req.AppName, client) {
+		span.SetAttributes(attribute.Bool("proxy.kill_switch", true))
+		variant = api.GetDefaultVariant()
+	} else {
+		unleashCtx := newUnleashContext(w, r, req)
+
+		// Resolve in the background, so the request can give up on a resolution that outlives its deadline
+		type variantResult struct {
+			variant *api.Variant
+			known   bool
+		}
+		results := make(chan variantResult, 1)
+		go func() {
+			variant, known := client.GetVariant(featureName, unleashCtx)
+			results <- variantResult{hookVariant(ctx, featureName, unleashCtx, variant, known), known}
+		}()
+
+		select {
+		case res := <-results:
+			if rejectUnknownFeature(w, r, span, log, featureName, req.AppName, res.known) {
+				return
+			}
+			variant = res.variant
+			label = featureLabel(featureName, res.known)
+		case <-ctx.Done():
+			abandonEvaluation(ctx, w, r, span, log.With("feature", featureName), req.AppName)
+			return
+		}
+	}
+
+	span.SetAttributes(
+		attribute.String("feature.variant", variant.Name),
+		attribute.Bool("feature.variant_enabled", variant.Enabled),
+		attribute.Bool("feature.enabled", variant.FeatureEnabled),
+	)
+
+	duration := time.Since(startTime)
+	metrics.RecordFeatureRequest(label, req.AppName, variant.FeatureEnabled, duration)
+
+	log.Log(ctx, checkLogLevel(duration), fmt.Sprintf("Variant check for %s - %s = %s", req.AppName, featureName, variant.Name),
+		"feature", featureName,
+		"variant", variant.Name,
+		"enabled", variant.FeatureEnabled,
+		"user_id", req.NavIdent,
+		"app_name", req.AppName,
+		"pod_name", req.PodName,
+		"duration", duration.Milliseconds(),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newVariantResponse(variant))
+}
+
+// hookVariant passes whether the feature is enabled through the registered hooks, as for a feature check.
+// A feature turned off by a hook resolves to the disabled variant. One turned on keeps its resolved variant,
+// or has no variant if Unleash had it disabled.
+func hookVariant(ctx context.Context, featureName string, unleashCtx unleashcontext.Context, variant *api.Variant, known bool) *api.Variant {
+	enabled := runHooks(ctx, Evaluation{
+		Feature: featureName,
+		Context: unleashCtx,
+		Enabled: variant.FeatureEnabled,
+		Known:   known,
+	})
+	if enabled == variant.FeatureEnabled {
+		return variant
+	}
+	if !enabled {
+		return api.GetDefaultVariant()
+	}
+
+	hooked := *api.GetDefaultVariant()
+	hooked.FeatureEnabled = true
+	return &hooked
+}
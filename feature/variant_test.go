@@ -0,0 +1,160 @@
+package feature
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Unleash/unleash-go-sdk/v5/api"
+	unleashcontext "github.com/Unleash/unleash-go-sdk/v5/context"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// checkVariant sends a variant request for the feature to VariantHandler and returns the recorded response.
+func checkVariant(name string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, PathPrefix+name+"/variant", strings.NewReader(body))
+	r.SetPathValue("name", name)
+	w := httptest.NewRecorder()
+	VariantHandler(w, r)
+	return w
+}
+
+func TestVariantHandler(t *testing.T) {
+	blue := &api.Variant{
+		Name:           "blue",
+		Enabled:        true,
+		FeatureEnabled: true,
+		Payload:        api.Payload{Type: "string", Value: "some-value"},
+	}
+
+	tests := []struct {
+		name       string
+		feature    string
+		killSwitch bool
+		want       VariantResponse
+	}{
+		{
+			name:    "variant",
+			feature: "colors",
+			want:    VariantResponse{Name: "blue", Enabled: true, Payload: VariantPayload{Type: "string", Value: "some-value"}},
+		},
+		{name: "disabled feature", feature: "dark-mode", want: VariantResponse{Name: "disabled"}},
+		{name: "unknown feature", feature: "missing", want: VariantResponse{Name: "disabled"}},
+		{name: "kill switch", feature: "colors", killSwitch: true, want: VariantResponse{Name: "disabled"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := map[string]bool{"colors": true, "dark-mode": false}
+			if tt.killSwitch {
+				withKillSwitch(t, time.Minute)
+				features[testKillSwitch] = false
+			}
+			evaluator := withEvaluator(t, features)
+			evaluator.Variants = map[string]*api.Variant{"colors": blue}
+
+			w := checkVariant(tt.feature, `{"appName":"`+testApp+`"}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, body %s", w.Code, w.Body)
+			}
+			var got VariantResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("variant = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// slowEvaluator is an Evaluator whose variants take delay to resolve.
+type slowEvaluator struct {
+	*clientstest.Evaluator
+	delay time.Duration
+}
+
+func (e slowEvaluator) GetVariant(name string, ctx unleashcontext.Context) (*api.Variant, bool) {
+	time.Sleep(e.delay)
+	return e.Evaluator.GetVariant(name, ctx)
+}
+
+func TestVariantHandlerTimesOut(t *testing.T) {
+	previous := requestTimeout
+	requestTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { requestTimeout = previous })
+	clients.RegisterEvaluator(testApp, slowEvaluator{clientstest.NewEvaluator(map[string]bool{"colors": true}), 100 * time.Millisecond})
+	t.Cleanup(func() { clients.UnregisterEvaluator(testApp) })
+
+	w := checkVariant("colors", `{"appName":"`+testApp+`"}`)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "timeout" {
+		t.Errorf("code = %q, want timeout", response.Code)
+	}
+}
+
+func TestVariantHandlerAppliesHooks(t *testing.T) {
+	evaluator := withEvaluator(t, map[string]bool{"colors": true})
+	evaluator.Variants = map[string]*api.Variant{"colors": {Name: "blue", Enabled: true, FeatureEnabled: true}}
+	withHooks(t, hookFunc(func(Evaluation) (bool, error) { return false, nil }))
+
+	w := checkVariant("colors", `{"appName":"`+testApp+`","navIdent":"Z123456"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var got VariantResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if want := (VariantResponse{Name: "disabled"}); got != want {
+		t.Errorf("variant = %+v, want %+v for a feature turned off by a hook", got, want)
+	}
+}
+
+func TestVariantHandlerRequireKnownFeature(t *testing.T) {
+	withEvaluator(t, map[string]bool{"colors": true})
+	clientstest.SetForTest(t, &env.RequireKnownFeature, true)
+
+	w := checkVariant("retired-ui", `{"appName":"`+testApp+`"}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Code != "unknown_feature" {
+		t.Errorf("code = %q, want unknown_feature", response.Code)
+	}
+}
+
+func TestVariantHandlerLogsNormalizedName(t *testing.T) {
+	clientstest.SetForTest(t, &nameNormalizer, newNameNormalizer("-", "._"))
+	withEvaluator(t, map[string]bool{"dark-mode": true})
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	if w := checkVariant("dark_mode", `{"appName":"`+testApp+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	if !strings.Contains(buf.String(), "Normalized feature name dark_mode to dark-mode") {
+		t.Errorf("normalization not logged: %s", buf.String())
+	}
+}
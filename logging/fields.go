@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// requestField builds a log attribute for a completed request.
+type requestField func(r *http.Request, status int, duration time.Duration) slog.Attr
+
+// availableRequestFields are the fields that can be selected with LOG_REQUEST_FIELDS.
+var availableRequestFields = map[string]requestField{
+	"method": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("method", r.Method)
+	},
+	"path": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("path", r.URL.Path)
+	},
+	"status": func(_ *http.Request, status int, _ time.Duration) slog.Attr {
+		return slog.Int("status", status)
+	},
+	"duration": func(_ *http.Request, _ int, duration time.Duration) slog.Attr {
+		return slog.Int64("duration", duration.Milliseconds())
+	},
+	"remote_addr": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("remote_addr", r.RemoteAddr)
+	},
+	"user_agent": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("user_agent", r.UserAgent())
+	},
+	"query": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("query", r.URL.RawQuery)
+	},
+	"host": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("host", r.Host)
+	},
+	"protocol": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("protocol", r.Proto)
+	},
+	"referer": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.String("referer", r.Referer())
+	},
+	"content_length": func(r *http.Request, _ int, _ time.Duration) slog.Attr {
+		return slog.Int64("content_length", r.ContentLength)
+	},
+}
+
+// defaultRequestFields are logged for every completed request unless LOG_REQUEST_FIELDS is set.
+var defaultRequestFields = []string{"method", "path", "status", "duration", "remote_addr", "user_agent"}
+
+// requestFieldNames are the fields logged for every completed request, in order.
+// It is set from LOG_REQUEST_FIELDS by Initialize, once warnings about it can be logged as JSON.
+var requestFieldNames = defaultRequestFields
+
+// parseRequestFields returns the selected request fields, ignoring unknown names with a warning.
+// Falls back to the default fields if none are selected, or none of the selected names are valid.
+func parseRequestFields(names []string) []string {
+	if len(names) == 0 {
+		return defaultRequestFields
+	}
+
+	var fields []string
+	for _, name := range names {
+		if _, ok := availableRequestFields[name]; !ok {
+			slog.Warn("Ignoring unknown LOG_REQUEST_FIELDS field "+name,
+				slog.String("field", name),
+			)
+			continue
+		}
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		slog.Warn("No valid LOG_REQUEST_FIELDS fields, using the defaults",
+			slog.Any("fields", defaultRequestFields),
+		)
+		return defaultRequestFields
+	}
+
+	return fields
+}
+
+// requestAttrs returns the configured log attributes for a completed request.
+func requestAttrs(r *http.Request, status int, duration time.Duration) []any {
+	attrs := make([]any, 0, len(requestFieldNames)+2)
+	for _, name := range requestFieldNames {
+		attrs = append(attrs, availableRequestFields[name](r, status, duration))
+	}
+	return attrs
+}
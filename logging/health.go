@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// healthWindow is the number of recent outcomes, successful responses and errors, the error rate is computed over.
+	healthWindow = 20
+
+	// healthFreshAge is how long since the last successful contact with Unleash a client counts as fully fresh.
+	// Clients fetch toggles every 15 seconds, and every successful response counts as contact.
+	healthFreshAge = 2 * time.Minute
+
+	// healthStaleAge is how long since the last successful contact a client counts as fully stale.
+	healthStaleAge = 10 * time.Minute
+)
+
+// health tracks signals about an Unleash client's connection to the Unleash server.
+// Successes are recorded for every successful response, see RecordContact, and errors for every SDK error.
+type health struct {
+	mu          sync.Mutex
+	ready       bool
+	lastSuccess time.Time
+	// lastReady is the time the client last became ready or registered with Unleash.
+	lastReady time.Time
+	lastError time.Time
+	// lastErrorMessage is the message of the client's last error.
+	lastErrorMessage string
+	// events is a ring of recent outcomes, true for errors.
+	events [healthWindow]bool
+	count  int
+	next   int
+}
+
+// record stores the outcome of a listener event.
+func (h *health) record(failed bool, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events[h.next] = failed
+	h.next = (h.next + 1) % healthWindow
+	h.count = min(h.count+1, healthWindow)
+
+	if failed {
+		h.lastError = now
+	} else {
+		h.lastSuccess = now
+	}
+}
+
+// recordError stores a failed listener event along with its error message.
+func (h *health) recordError(message string, now time.Time) {
+	h.record(true, now)
+
+	h.mu.Lock()
+	h.lastErrorMessage = message
+	h.mu.Unlock()
+}
+
+// markReady records that the client has fetched its toggles for the first time.
+// The fetch itself has already been recorded as a success.
+func (h *health) markReady(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ready = true
+	h.lastReady = now
+}
+
+// markRegistered records that the client has registered with Unleash.
+// The registration itself has already been recorded as a success.
+func (h *health) markRegistered(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastReady = now
+}
+
+// lastSuccessAt returns the time of the last successful contact with Unleash, or the zero time if there has been none.
+func (h *health) lastSuccessAt() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastSuccess
+}
+
+// lastReadyAt returns the time the client last became ready or registered, or the zero time if neither has happened.
+func (h *health) lastReadyAt() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastReady
+}
+
+// lastErrorAt returns the time and message of the client's last error, or the zero time if there has been none.
+func (h *health) lastErrorAt() (time.Time, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastError, h.lastErrorMessage
+}
+
+// isReady reports whether the client has fetched its toggles.
+func (h *health) isReady() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.ready
+}
+
+// score combines readiness, time since last successful contact and recent error rate into a value between 0 and 1:
+//
+//	score = 0                                  if the client is not ready
+//	score = freshness * (1 - errorRate)        otherwise
+//
+// freshness is 1 up to healthFreshAge since the last successful contact, then falls linearly to 0 at healthStaleAge.
+// errorRate is the share of errors among the last healthWindow outcomes.
+func (h *health) score(now time.Time) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.ready {
+		return 0
+	}
+
+	freshness := 1.0
+	if age := now.Sub(h.lastSuccess); age > healthFreshAge {
+		freshness = max(0, 1-float64(age-healthFreshAge)/float64(healthStaleAge-healthFreshAge))
+	}
+
+	errors := 0
+	for _, failed := range h.events[:h.count] {
+		if failed {
+			errors++
+		}
+	}
+
+	errorRate := 0.0
+	if h.count > 0 {
+		errorRate = float64(errors) / float64(h.count)
+	}
+
+	return freshness * (1 - errorRate)
+}
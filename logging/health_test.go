@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHealthScore(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	midway := healthFreshAge + (healthStaleAge-healthFreshAge)/2
+
+	tests := []struct {
+		name      string
+		ready     bool
+		sinceLast time.Duration
+		errors    int
+		successes int
+		want      float64
+	}{
+		{name: "not ready", ready: false, successes: 1, want: 0},
+		{name: "fresh without errors", ready: true, successes: 1, want: 1},
+		{name: "fresh at the fresh age", ready: true, sinceLast: healthFreshAge, successes: 1, want: 1},
+		{name: "halfway stale", ready: true, sinceLast: midway, successes: 1, want: 0.5},
+		{name: "stale", ready: true, sinceLast: healthStaleAge, successes: 1, want: 0},
+		{name: "older than stale", ready: true, sinceLast: 2 * healthStaleAge, successes: 1, want: 0},
+		{name: "quarter errors", ready: true, errors: 1, successes: 3, want: 0.75},
+		{name: "halfway stale with quarter errors", ready: true, sinceLast: midway, errors: 1, successes: 3, want: 0.375},
+		{name: "only the window counts", ready: true, errors: healthWindow, successes: healthWindow, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h health
+			start := now.Add(-tt.sinceLast - time.Second)
+			for range tt.errors {
+				h.record(true, start)
+			}
+			for range tt.successes {
+				h.record(false, now.Add(-tt.sinceLast))
+			}
+			if tt.ready {
+				h.markReady(start)
+			}
+
+			if got := h.score(now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
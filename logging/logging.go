@@ -6,16 +6,44 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/navikt/klage-unleash-proxy/env"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// Initialize sets up the default JSON logger
+// jsonHandler writes log records as JSON to stdout. It is set by Initialize.
+var jsonHandler slog.Handler
+
+// defaultAttrs returns the attributes added to every log record.
+func defaultAttrs() []any {
+	return []any{slog.String("app_version", env.AppVersion)}
+}
+
+// parseLevel parses LOG_LEVEL (debug, info, warn or error) into a slog.Level.
+// Returns false for unrecognized values.
+func parseLevel(value string) (slog.Level, bool) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// Initialize sets up the default JSON logger, at the level from LOG_LEVEL (default info)
 func Initialize() *slog.Logger {
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+	level, validLevel := parseLevel(env.LogLevel)
+
+	jsonHandler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			if a.Key == slog.MessageKey {
 				a.Key = "message"
@@ -24,22 +52,58 @@ func Initialize() *slog.Logger {
 		},
 	})
 
-	logger := slog.New(handler).With(
-		slog.String("app_version", env.AppVersion),
-	)
+	logger := slog.New(jsonHandler).With(defaultAttrs()...)
 
 	slog.SetDefault(logger)
 
+	if !validLevel {
+		logger.Warn("Invalid LOG_LEVEL, using info",
+			slog.String("value", env.LogLevel),
+		)
+	}
+
+	requestFieldNames = parseRequestFields(env.LogRequestFields)
+
 	return logger
 }
 
+type verboseKey struct{}
+
+// WithVerbose returns a context for which FromContext loggers log at Debug level,
+// regardless of the global log level. Use this for targeted debugging of a single request.
+func WithVerbose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verboseKey{}, true)
+}
+
+// verboseHandler wraps a slog.Handler to enable all levels from Debug and up.
+type verboseHandler struct {
+	slog.Handler
+}
+
+func (h verboseHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug
+}
+
+func (h verboseHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return verboseHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h verboseHandler) WithGroup(name string) slog.Handler {
+	return verboseHandler{h.Handler.WithGroup(name)}
+}
+
 // FromContext returns a logger with trace_id and span_id attributes if available in the context.
 // Use this when logging from handlers to correlate logs with traces.
 func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if verbose, _ := ctx.Value(verboseKey{}).(bool); verbose {
+		logger = slog.New(verboseHandler{logger.Handler()})
+	}
+
 	spanCtx := trace.SpanContextFromContext(ctx)
 
 	if !spanCtx.HasTraceID() && !spanCtx.HasSpanID() {
-		return slog.Default()
+		return logger
 	}
 
 	var attrs []any
@@ -50,7 +114,7 @@ func FromContext(ctx context.Context) *slog.Logger {
 		attrs = append(attrs, slog.String("span_id", spanCtx.SpanID().String()))
 	}
 
-	return slog.Default().With(attrs...)
+	return logger.With(attrs...)
 }
 
 // responseWriter wraps http.ResponseWriter to capture the status code
@@ -64,9 +128,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// shouldSkipLogging returns true for health check endpoints that should not be logged
+// shouldSkipLogging returns true for health check and introspection endpoints that should not be logged
 func shouldSkipLogging(path string) bool {
-	return path == "/isAlive" || path == "/isReady" || path == "/metrics"
+	return path == "/" || path == "/isAlive" || path == "/isReady" || path == "/metrics" || path == "/admin/routes" || path == "/status"
 }
 
 // Middleware returns an HTTP middleware that logs each request with timing information
@@ -91,14 +155,7 @@ func Middleware(next http.Handler) http.Handler {
 
 		// Get trace ID from context if available
 		spanCtx := trace.SpanContextFromContext(r.Context())
-		logAttrs := []any{
-			slog.String("method", r.Method),
-			slog.String("path", r.URL.Path),
-			slog.Int("status", wrapped.statusCode),
-			slog.Int64("duration", duration.Milliseconds()),
-			slog.String("remote_addr", r.RemoteAddr),
-			slog.String("user_agent", r.UserAgent()),
-		}
+		logAttrs := requestAttrs(r, wrapped.statusCode, duration)
 
 		if spanCtx.HasTraceID() {
 			logAttrs = append(logAttrs, slog.String("trace_id", spanCtx.TraceID().String()))
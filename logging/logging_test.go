@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLogs makes the default logger write JSON at info level to the returned buffer for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      slog.Level
+		wantValid bool
+	}{
+		{value: "", want: slog.LevelInfo, wantValid: true},
+		{value: "debug", want: slog.LevelDebug, wantValid: true},
+		{value: "DEBUG", want: slog.LevelDebug, wantValid: true},
+		{value: "info", want: slog.LevelInfo, wantValid: true},
+		{value: "warn", want: slog.LevelWarn, wantValid: true},
+		{value: "Error", want: slog.LevelError, wantValid: true},
+		{value: "warning", want: slog.LevelInfo, wantValid: false},
+		{value: "trace", want: slog.LevelInfo, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, valid := parseLevel(tt.value)
+			if got != tt.want || valid != tt.wantValid {
+				t.Errorf("parseLevel(%q) = %v, %v, want %v, %v", tt.value, got, valid, tt.want, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestFromContextVerbose(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantLog bool
+	}{
+		{name: "default", ctx: context.Background(), wantLog: false},
+		{name: "verbose", ctx: WithVerbose(context.Background()), wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t)
+
+			FromContext(tt.ctx).With("key", "value").Debug("debug message")
+
+			if got := strings.Contains(buf.String(), "debug message"); got != tt.wantLog {
+				t.Errorf("logged debug message = %v, want %v: %s", got, tt.wantLog, buf)
+			}
+		})
+	}
+}
+
+func TestParseRequestFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{name: "unset", names: nil, want: defaultRequestFields},
+		{name: "selected in order", names: []string{"status", "method"}, want: []string{"status", "method"}},
+		{name: "duplicates", names: []string{"path", "path", "host"}, want: []string{"path", "host"}},
+		{name: "unknown ignored", names: []string{"path", "password"}, want: []string{"path"}},
+		{name: "only unknown", names: []string{"password"}, want: defaultRequestFields},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			if got := parseRequestFields(tt.names); !slices.Equal(got, tt.want) {
+				t.Errorf("parseRequestFields(%v) = %v, want %v", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestAttrs(t *testing.T) {
+	previous := requestFieldNames
+	requestFieldNames = []string{"status", "query", "duration"}
+	t.Cleanup(func() { requestFieldNames = previous })
+
+	r := httptest.NewRequest(http.MethodGet, "/features/new-ui?appName=kabal-api", nil)
+	attrs := requestAttrs(r, http.StatusTeapot, 1500*time.Millisecond)
+
+	want := []slog.Attr{
+		slog.Int("status", http.StatusTeapot),
+		slog.String("query", "appName=kabal-api"),
+		slog.Int64("duration", 1500),
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("requestAttrs = %v, want %v", attrs, want)
+	}
+	for i, attr := range attrs {
+		if !attr.(slog.Attr).Equal(want[i]) {
+			t.Errorf("attribute %d = %v, want %v", i, attr, want[i])
+		}
+	}
+}
+
+func TestMiddlewareSkipsHealthAndIntrospectionEndpoints(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantLog bool
+	}{
+		{path: "/", wantLog: false},
+		{path: "/isAlive", wantLog: false},
+		{path: "/isReady", wantLog: false},
+		{path: "/metrics", wantLog: false},
+		{path: "/admin/routes", wantLog: false},
+		{path: "/status", wantLog: false},
+		{path: "/features/new-ui", wantLog: true},
+		{path: "/admin/stats", wantLog: true},
+		{path: "/isReady/", wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf := captureLogs(t)
+
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusAccepted {
+				t.Errorf("status %d, want the handler's %d", w.Code, http.StatusAccepted)
+			}
+			if logged := buf.Len() > 0; logged != tt.wantLog {
+				t.Errorf("logged = %v, want %v: %s", logged, tt.wantLog, buf)
+			}
+			if !tt.wantLog {
+				return
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("decoding log record: %v", err)
+			}
+			if record["path"] != tt.path || record["status"] != float64(http.StatusAccepted) {
+				t.Errorf("log record %v, want path %s and status %d", record, tt.path, http.StatusAccepted)
+			}
+		})
+	}
+}
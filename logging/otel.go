@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/log"
+)
+
+// EnableOTel sends every log record to the given OpenTelemetry logger provider
+// in addition to the stdout JSON logger. Call this after telemetry.Initialize().
+func EnableOTel(name string, provider log.LoggerProvider) {
+	handler := teeHandler{
+		jsonHandler,
+		otelslog.NewHandler(name, otelslog.WithLoggerProvider(provider)),
+	}
+
+	slog.SetDefault(slog.New(handler).With(defaultAttrs()...))
+}
+
+// teeHandler passes every log record to all its handlers.
+// The first handler decides which levels are enabled, and records are not filtered again per handler.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return t[0].Enabled(ctx, level)
+}
+
+func (t teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		errs = append(errs, h.Handle(ctx, record.Clone()))
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// stubExporter is an OpenTelemetry log exporter that keeps the records it is given.
+type stubExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *stubExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *stubExporter) Shutdown(context.Context) error   { return nil }
+func (e *stubExporter) ForceFlush(context.Context) error { return nil }
+
+func TestEnableOTelExportsRecordsAndKeepsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	previousHandler, previousDefault := jsonHandler, slog.Default()
+	jsonHandler = slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	t.Cleanup(func() {
+		jsonHandler = previousHandler
+		slog.SetDefault(previousDefault)
+	})
+
+	exporter := &stubExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	EnableOTel("test", provider)
+	slog.Info("exported message", slog.String("app_name", "kabal-api"))
+	slog.Debug("filtered message")
+
+	if !strings.Contains(buf.String(), "exported message") {
+		t.Errorf("stdout JSON log %q, want the record", buf.String())
+	}
+	if strings.Contains(buf.String(), "filtered message") {
+		t.Errorf("stdout JSON log %q, want records below the level dropped", buf.String())
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	if len(exporter.records) != 1 {
+		t.Fatalf("exported %d records, want 1", len(exporter.records))
+	}
+	record := exporter.records[0]
+	if got := record.Body().AsString(); got != "exported message" {
+		t.Errorf("exported body %q, want exported message", got)
+	}
+	attrs := map[string]string{}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.AsString()
+		return true
+	})
+	if attrs["app_name"] != "kabal-api" {
+		t.Errorf("exported attributes %v, want app_name", attrs)
+	}
+	if _, ok := attrs["app_version"]; !ok {
+		t.Errorf("exported attributes %v, want the default app_version", attrs)
+	}
+}
@@ -3,6 +3,8 @@ package logging
 import (
 	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Unleash/unleash-go-sdk/v5"
 )
@@ -10,47 +12,107 @@ import (
 // SlogListener implements the unleash.Listener interface using slog for logging
 type SlogListener struct {
 	appName string
+	// logger carries the client's identifying fields, so every callback line is self-describing
+	// even though callbacks fire on background goroutines without request context.
+	logger *slog.Logger
+	health health
+	// ready is closed when the client has fetched its toggles for the first time.
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	registrationMu sync.Mutex
+	// registration is the client data sent to Unleash on registration, nil until the client has registered.
+	registration *unleash.ClientData
 }
 
 // OnError is called when an error occurs in the Unleash client
 func (l *SlogListener) OnError(err error) {
 	errMsg := err.Error()
 
+	l.health.recordError(errMsg, time.Now())
+
 	// Treat retry/backoff errors as warnings since they are transient
 	// The SDK uses these phrases when backing off due to 429 or 5xx errors
 	if strings.Contains(errMsg, "backing off") {
-		slog.Warn("Unleash request retry for "+l.appName,
-			slog.String("app_name", l.appName),
+		l.logger.Warn("Unleash request retry for "+l.appName,
 			slog.String("warning", errMsg),
 		)
 		return
 	}
 
-	slog.Error("Unleash error for "+l.appName,
-		slog.String("app_name", l.appName),
+	l.logger.Error("Unleash error for "+l.appName,
 		slog.String("error", errMsg),
 	)
 }
 
 // OnWarning is called when a warning occurs in the Unleash client
 func (l *SlogListener) OnWarning(warning error) {
-	slog.Warn("Unleash warning for "+l.appName,
-		slog.String("app_name", l.appName),
+	l.logger.Warn("Unleash warning for "+l.appName,
 		slog.String("warning", warning.Error()),
 	)
 }
 
 // OnReady is called when the Unleash client is ready
 func (l *SlogListener) OnReady() {
-	slog.Info("Unleash client ready for "+l.appName,
-		slog.String("app_name", l.appName),
-	)
+	l.health.markReady(time.Now())
+	l.readyOnce.Do(func() { close(l.ready) })
+	l.logger.Info("Unleash client ready for " + l.appName)
+}
+
+// OnUpdate is called when the Unleash client receives changed feature toggles
+func (l *SlogListener) OnUpdate() {
+	l.logger.Debug("Unleash features updated for " + l.appName)
+}
+
+// Health returns the client's health score between 0 and 1, see health.score for the formula.
+func (l *SlogListener) Health() float64 {
+	return l.health.score(time.Now())
+}
+
+// RecordContact records a successful response from the Unleash server, including a 304 Not Modified
+// toggle fetch, which the SDK reports no event for. The client's HTTP transport calls it for every response.
+func (l *SlogListener) RecordContact() {
+	l.health.record(false, time.Now())
+}
+
+// LastSuccess returns the time of the client's last successful response from the Unleash server:
+// fetching toggles, even if unchanged, sending metrics or registering.
+func (l *SlogListener) LastSuccess() time.Time {
+	return l.health.lastSuccessAt()
+}
+
+// LastReady returns the time the client last became ready or registered with the Unleash server,
+// or the zero time if neither has happened.
+func (l *SlogListener) LastReady() time.Time {
+	return l.health.lastReadyAt()
+}
+
+// LastError returns the time of the client's last error, or the zero time if there has been none.
+func (l *SlogListener) LastError() time.Time {
+	at, _ := l.health.lastErrorAt()
+	return at
+}
+
+// LastErrorMessage returns the message of the client's last error, or an empty string if there has been none.
+func (l *SlogListener) LastErrorMessage() string {
+	_, message := l.health.lastErrorAt()
+	return message
+}
+
+// Ready reports whether the client has fetched its toggles from the Unleash server.
+func (l *SlogListener) Ready() bool {
+	return l.health.isReady()
+}
+
+// ReadyChan returns a channel that is closed once the client has fetched its toggles from the Unleash server.
+// Unlike the SDK's WaitForReady, it can be waited on with a timeout.
+func (l *SlogListener) ReadyChan() <-chan struct{} {
+	return l.ready
 }
 
 // OnCount is called when feature toggles are counted
 func (l *SlogListener) OnCount(name string, enabled bool) {
-	slog.Debug("Unleash feature count for "+l.appName,
-		slog.String("app_name", l.appName),
+	l.logger.Debug("Unleash feature count for "+l.appName,
 		slog.String("feature", name),
 		slog.Bool("enabled", enabled),
 	)
@@ -58,8 +120,7 @@ func (l *SlogListener) OnCount(name string, enabled bool) {
 
 // OnSent is called when metrics are sent to the Unleash server
 func (l *SlogListener) OnSent(payload unleash.MetricsData) {
-	slog.Debug("Unleash metrics sent for "+l.appName,
-		slog.String("app_name", l.appName),
+	l.logger.Debug("Unleash metrics sent for "+l.appName,
 		slog.Time("start", payload.Bucket.Start),
 		slog.Time("stop", payload.Bucket.Stop),
 		slog.Int("toggles", len(payload.Bucket.Toggles)),
@@ -68,9 +129,13 @@ func (l *SlogListener) OnSent(payload unleash.MetricsData) {
 
 // OnRegistered is called when the client is registered with the Unleash server
 func (l *SlogListener) OnRegistered(payload unleash.ClientData) {
-	slog.Info("Unleash client registered for "+l.appName,
-		slog.String("app_name", l.appName),
-		slog.String("instance_id", payload.InstanceID),
+	l.health.markRegistered(time.Now())
+
+	l.registrationMu.Lock()
+	l.registration = &payload
+	l.registrationMu.Unlock()
+
+	l.logger.Info("Unleash client registered for "+l.appName,
 		slog.String("sdk_version", payload.SDKVersion),
 		slog.Any("strategies", payload.Strategies),
 		slog.Time("started", payload.Started),
@@ -78,9 +143,28 @@ func (l *SlogListener) OnRegistered(payload unleash.ClientData) {
 	)
 }
 
-// NewSlogListener creates a new SlogListener with the given app name
-func NewSlogListener(appName string) *SlogListener {
+// Registration returns the client data the client registered with, and false if it has not registered yet.
+func (l *SlogListener) Registration() (unleash.ClientData, bool) {
+	l.registrationMu.Lock()
+	defer l.registrationMu.Unlock()
+
+	if l.registration == nil {
+		return unleash.ClientData{}, false
+	}
+	return *l.registration, true
+}
+
+// NewSlogListener creates a new SlogListener for the client of the given app,
+// logging with the client's instance ID, Unleash environment and Unleash URL on every line.
+func NewSlogListener(appName, instanceID, environment, url string) *SlogListener {
 	return &SlogListener{
 		appName: appName,
+		ready:   make(chan struct{}),
+		logger: slog.Default().With(
+			slog.String("app_name", appName),
+			slog.String("instance_id", instanceID),
+			slog.String("environment", environment),
+			slog.String("url", url),
+		),
 	}
 }
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Unleash/unleash-go-sdk/v5"
+)
+
+func TestSlogListenerLogsClientFields(t *testing.T) {
+	buf := captureLogs(t)
+
+	listener := NewSlogListener("kabal-api", "pod-abc123", "development", "https://unleash.example/api")
+	listener.OnReady()
+	listener.OnWarning(errors.New("slow response"))
+	listener.OnError(errors.New("connection refused"))
+	listener.OnError(errors.New("backing off"))
+	listener.OnRegistered(unleash.ClientData{SDKVersion: "unleash-go-sdk:v5"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("logged %d lines, want one per callback: %s", len(lines), buf)
+	}
+
+	want := map[string]string{
+		"app_name":    "kabal-api",
+		"instance_id": "pod-abc123",
+		"environment": "development",
+		"url":         "https://unleash.example/api",
+	}
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding log record: %v", err)
+		}
+		for key, value := range want {
+			if record[key] != value {
+				t.Errorf("%s = %v, want %q in %s", key, record[key], value, line)
+			}
+		}
+	}
+}
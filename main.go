@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -12,17 +13,20 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/navikt/klage-unleash-proxy/admin"
 	"github.com/navikt/klage-unleash-proxy/clients"
 	"github.com/navikt/klage-unleash-proxy/env"
 	"github.com/navikt/klage-unleash-proxy/feature"
 	"github.com/navikt/klage-unleash-proxy/logging"
-	_ "github.com/navikt/klage-unleash-proxy/metrics" // Register Prometheus metrics
-	"github.com/navikt/klage-unleash-proxy/nais"
+	"github.com/navikt/klage-unleash-proxy/metrics"
 	"github.com/navikt/klage-unleash-proxy/telemetry"
 )
 
 var okBytes = []byte("OK")
 
+// telemetryShutdownTimeout bounds how long shutdown waits for OpenTelemetry exporters to flush.
+var telemetryShutdownTimeout = 5 * time.Second
+
 func init() {
 	// Initialize JSON logger
 	logging.Initialize()
@@ -34,17 +38,87 @@ func livenessHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(okBytes)
 }
 
+// serviceInfo is the JSON response for the root path, identifying the service for smoke tests.
+type serviceInfo struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Endpoints map[string]string `json:"endpoints"`
+}
+
+// rootHandler handles GET /, responding with the service name, version and main endpoints.
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	name := env.NaisAppName
+	if name == "" {
+		name = env.DefaultServiceName
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(serviceInfo{
+		Name:    name,
+		Version: env.AppVersion,
+		Endpoints: map[string]string{
+			"liveness":  "/isAlive",
+			"readiness": "/isReady",
+			"feature":   feature.PathPrefix + "{featureName}",
+		},
+	})
+}
+
+// shedThreshold is the number of in-flight feature requests at which readiness is reported as failing,
+// letting Kubernetes route traffic to other pods. Zero disables load shedding.
+var shedThreshold int
+
+// shutdownTimeout bounds graceful shutdown of the HTTP server, and should fit within the pod's terminationGracePeriodSeconds.
+var shutdownTimeout time.Duration
+
+// staleThreshold is how long a ready Unleash client may go without successful contact with Unleash
+// before readiness is reported as failing. Zero disables the check.
+var staleThreshold time.Duration
+
+// readinessResponse lists which apps' Unleash clients are ready, and which of those are stale.
+type readinessResponse struct {
+	Ready    []string `json:"ready"`
+	NotReady []string `json:"not_ready"`
+	Stale    []string `json:"stale"`
+}
+
+// readinessHandler reports ready as soon as at least one Unleash client is ready,
+// so one unreachable app does not take down the proxy for all the others.
 func readinessHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
+	readyApps, notReadyApps := clients.ReadyApps()
 
-	if !clients.Ready() {
+	if len(readyApps) == 0 {
+		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusServiceUnavailable)
 		w.Write([]byte("NOT READY"))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(okBytes)
+	if shedThreshold > 0 && feature.InFlight() >= int64(shedThreshold) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("OVERLOADED"))
+		return
+	}
+
+	staleApps := []string{}
+	if staleThreshold > 0 {
+		staleApps = clients.StaleApps(staleThreshold)
+	}
+
+	status := http.StatusOK
+	if len(staleApps) > 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(readinessResponse{
+		Ready:    readyApps,
+		NotReady: notReadyApps,
+		Stale:    staleApps,
+	})
 }
 
 func initializeClients() {
@@ -55,10 +129,141 @@ func initializeClients() {
 		os.Exit(1)
 	}
 
-	slog.Info(fmt.Sprintf("All %d Unleash clients ready", len(nais.InboundApps)))
+	readyApps, notReadyApps := clients.ReadyApps()
+	slog.Info(fmt.Sprintf("%d of %d Unleash clients ready", len(readyApps), len(readyApps)+len(notReadyApps)),
+		slog.Any("not_ready", notReadyApps),
+	)
+}
+
+// shutdownTelemetry shuts down OpenTelemetry with its own deadline,
+// so a stuck exporter cannot prevent the process from exiting.
+func shutdownTelemetry(otelInstance *telemetry.Telemetry) {
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- otelInstance.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			slog.Error("OpenTelemetry shutdown error",
+				slog.String("error", err.Error()),
+			)
+		}
+	case <-ctx.Done():
+		slog.Warn("OpenTelemetry shutdown did not finish in time, abandoning it",
+			slog.Duration("timeout", telemetryShutdownTimeout),
+		)
+	}
+}
+
+// newHandler registers all routes on a new mux and wraps it in the middleware chain.
+// otelMiddleware is nil when OpenTelemetry middleware could not be created.
+func newHandler(otelMiddleware *telemetry.Middleware) http.Handler {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	// Build the handler chain
+	// Order matters: OTel middleware must run first (outermost) to create the trace context,
+	// then logging middleware can access the trace ID from the context
+	var handler http.Handler = mux
+	handler = logging.Middleware(handler)
+	if otelMiddleware != nil {
+		handler = otelMiddleware.Handler(handler)
+	}
+
+	return handler
+}
+
+// registerRoutes registers all routes and the 404 catch-all on the mux, and logs the registered routes.
+func registerRoutes(mux *http.ServeMux) *admin.Routes {
+	routes := admin.NewRoutes(mux)
+
+	// The exact root only, so other unknown paths still reach the 404 catch-all
+	routes.Handle("/{$}", []string{http.MethodGet}, http.HandlerFunc(rootHandler))
+	routes.Handle("/isAlive", []string{http.MethodGet}, http.HandlerFunc(livenessHandler))
+	routes.Handle("/isReady", []string{http.MethodGet}, http.HandlerFunc(readinessHandler))
+
+	routes.Handle("/metrics", []string{http.MethodGet}, promhttp.Handler())
+	routes.Handle("/status", []string{http.MethodGet}, admin.RequireToken(http.HandlerFunc(admin.StatusHandler)))
+
+	routes.Handle("/admin/flush-metrics", []string{http.MethodPost}, admin.RequireToken(http.HandlerFunc(admin.FlushMetricsHandler)))
+	routes.Handle("/admin/reload", []string{http.MethodPost}, admin.RequireToken(http.HandlerFunc(admin.ReloadHandler)))
+	routes.Handle("/admin/selftest", []string{http.MethodGet}, admin.RequireToken(http.HandlerFunc(admin.SelfTestHandler)))
+	routes.Handle("/admin/sdk-info", []string{http.MethodGet}, admin.RequireToken(http.HandlerFunc(admin.SDKInfoHandler)))
+	routes.Handle("/admin/stats", []string{http.MethodGet}, admin.RequireToken(http.HandlerFunc(feature.StatsHandler)))
+	routes.Handle("/admin/routes", []string{http.MethodGet}, admin.RequireToken(routes.Handler()))
+
+	routes.Handle(feature.PathPrefix, []string{http.MethodPost, "QUERY", http.MethodGet}, metrics.StatusMiddleware(http.HandlerFunc(feature.Handler)))
+	routes.Handle(feature.VariantPath, []string{http.MethodPost, "QUERY"}, metrics.StatusMiddleware(http.HandlerFunc(feature.VariantHandler)))
+	routes.Handle(feature.BatchPath, []string{http.MethodPost}, metrics.StatusMiddleware(http.HandlerFunc(feature.BatchHandler)))
+
+	slog.Info(fmt.Sprintf("Registered %d routes", len(routes.List())),
+		slog.Any("routes", routes.List()),
+	)
+
+	mux.Handle("/", routes.NotFoundHandler())
+
+	return routes
+}
+
+// newServer creates the HTTP server for the handler, with h2c enabled if ENABLE_H2C is set.
+func newServer(addr string, handler http.Handler) *http.Server {
+	// Timeouts protect against slow clients holding connections open, e.g. slowloris
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: env.Duration("SERVER_READ_HEADER_TIMEOUT", env.ServerReadHeaderTimeout, 5*time.Second),
+		ReadTimeout:       env.Duration("SERVER_READ_TIMEOUT", env.ServerReadTimeout, 10*time.Second),
+		WriteTimeout:      env.Duration("SERVER_WRITE_TIMEOUT", env.ServerWriteTimeout, 10*time.Second),
+		IdleTimeout:       env.Duration("SERVER_IDLE_TIMEOUT", env.ServerIdleTimeout, 120*time.Second),
+	}
+
+	// Allow HTTP/2 cleartext (h2c) for callers that multiplex over fewer connections.
+	// The server's own protocol support keeps graceful shutdown working for h2c connections.
+	if env.EnableH2C {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server.Protocols = protocols
+	}
+
+	return server
+}
+
+// handleSignals waits for the first shutdown signal and runs the graceful shutdown.
+// A second signal calls exit(1) immediately, in case graceful shutdown is stuck.
+func handleSignals(signals <-chan os.Signal, shutdown func(), exit func(code int)) {
+	<-signals
+	slog.Info("Received shutdown signal, shutting down gracefully...")
+
+	go func() {
+		sig := <-signals
+		slog.Warn("Received second shutdown signal, exiting immediately",
+			slog.String("signal", sig.String()),
+		)
+		exit(1)
+	}()
+
+	shutdown()
 }
 
 func main() {
+	// Fail fast on missing configuration, rather than with confusing client errors after startup
+	if err := env.Validate(); err != nil {
+		slog.Error("Invalid configuration: "+err.Error(),
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
+
+	// Settings with defaults are read once logging is set up, so warnings about invalid values are logged as JSON
+	clients.Configure()
+	feature.Configure()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -72,9 +277,35 @@ func main() {
 		// Continue without telemetry rather than failing
 	}
 
+	// Export logs through OpenTelemetry as well, keeping stdout JSON logging
+	if otelInstance != nil && otelInstance.LoggerProvider != nil {
+		logging.EnableOTel(otelConfig.ServiceName, otelInstance.LoggerProvider)
+	}
+
+	shedThreshold = env.Int("READINESS_SHED_THRESHOLD", env.ReadinessShedThreshold, 0)
+	staleThreshold = env.Duration("READINESS_STALE_THRESHOLD", env.ReadinessStaleThreshold, 0)
+	shutdownTimeout = env.Duration("SHUTDOWN_TIMEOUT", env.ShutdownTimeout, 30*time.Second)
+
 	// Initialize tracer after OpenTelemetry initialization
 	feature.InitTracer()
 
+	feature.StartDecisionSink()
+
+	if len(env.ForceEnabledNavIdents) > 0 {
+		feature.RegisterHook(feature.NewForceEnabledHook(env.ForceEnabledNavIdents))
+	}
+
+	overrideHook, err := feature.OverrideHookFromEnv()
+	if err != nil {
+		slog.Error("Invalid configuration: "+err.Error(),
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
+	if overrideHook != nil {
+		feature.RegisterHook(overrideHook)
+	}
+
 	// Create OpenTelemetry middleware
 	otelMiddleware, err := telemetry.NewMiddleware(otelInstance != nil)
 	if err != nil {
@@ -83,43 +314,19 @@ func main() {
 		)
 	}
 
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("/isAlive", livenessHandler)
-	mux.HandleFunc("/isReady", readinessHandler)
-
-	mux.Handle("/metrics", promhttp.Handler())
-
-	mux.HandleFunc(feature.PathPrefix, feature.Handler)
-
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.NotFound(w, r)
-	})
-
 	port := env.Port
 	if port == "" {
 		port = env.DefaultPort
 	}
 
-	// Build the handler chain
-	// Order matters: OTel middleware must run first (outermost) to create the trace context,
-	// then logging middleware can access the trace ID from the context
-	var handler http.Handler = mux
-	handler = logging.Middleware(handler)
-	if otelMiddleware != nil {
-		handler = otelMiddleware.Handler(handler)
-	}
-
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: handler,
-	}
+	server := newServer(":"+port, newHandler(otelMiddleware))
 
 	// Start server in a goroutine so we can initialize clients while serving health checks
 	go func() {
 		slog.Info("Starting server",
 			slog.String("port", port),
 			slog.Bool("otel_enabled", otelInstance != nil),
+			slog.Bool("h2c_enabled", env.EnableH2C),
 		)
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -134,15 +341,12 @@ func main() {
 	initializeClients()
 
 	// Handle graceful shutdown
-	signalChannel := make(chan os.Signal, 1)
+	signalChannel := make(chan os.Signal, 2)
 	signal.Notify(signalChannel, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		<-signalChannel
-		slog.Info("Received shutdown signal, shutting down gracefully...")
-
+	go handleSignals(signalChannel, func() {
 		// Create a deadline for graceful shutdown
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer shutdownCancel()
 
 		// Shutdown the HTTP server
@@ -152,20 +356,19 @@ func main() {
 			)
 		}
 
+		// Post the remaining evaluation decisions, now that no more requests are handled
+		feature.StopDecisionSink(shutdownCtx)
+
 		// Close all Unleash clients
 		clients.Close()
 
 		// Shutdown OpenTelemetry
 		if otelInstance != nil {
-			if err := otelInstance.Shutdown(shutdownCtx); err != nil {
-				slog.Error("OpenTelemetry shutdown error",
-					slog.String("error", err.Error()),
-				)
-			}
+			shutdownTelemetry(otelInstance)
 		}
 
 		cancel()
-	}()
+	}, os.Exit)
 
 	// Wait for shutdown signal
 	<-ctx.Done()
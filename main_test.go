@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/navikt/klage-unleash-proxy/admin"
+	"github.com/navikt/klage-unleash-proxy/clients"
+	"github.com/navikt/klage-unleash-proxy/clients/clientstest"
+	"github.com/navikt/klage-unleash-proxy/env"
+	"github.com/navikt/klage-unleash-proxy/feature"
+	"github.com/navikt/klage-unleash-proxy/telemetry"
+)
+
+func TestH2C(t *testing.T) {
+	defer func(enabled bool) { env.EnableH2C = enabled }(env.EnableH2C)
+	env.EnableH2C = true
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	server := newServer(listener.Addr().String(), newHandler(nil))
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get("http://" + listener.Addr().String() + "/isAlive")
+	if err != nil {
+		t.Fatalf("GET /isAlive over h2c: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Graceful shutdown must close the idle h2c connection rather than wait out the timeout
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve = %v, want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	feature.InitTracer()
+	clients.RegisterEvaluator("kabal-frontend", clientstest.NewEvaluator(map[string]bool{"new-ui": true}))
+	defer clients.UnregisterEvaluator("kabal-frontend")
+
+	server := httptest.NewServer(newHandler(nil))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+feature.PathPrefix+"new-ui", "application/json", strings.NewReader(`{"appName":"kabal-frontend"}`))
+	if err != nil {
+		t.Fatalf("POST feature: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("feature status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading /metrics: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	for _, name := range []string{"feature_requests_total", "feature_responses_total"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("/metrics does not expose %s", name)
+		}
+	}
+}
+
+func checkReadiness(t *testing.T) (int, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	readinessHandler(w, httptest.NewRequest(http.MethodGet, "/isReady", nil))
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	t.Run("no clients", func(t *testing.T) {
+		status, body := checkReadiness(t)
+		if status != http.StatusServiceUnavailable || body != "NOT READY" {
+			t.Errorf("got %d %q, want %d %q", status, body, http.StatusServiceUnavailable, "NOT READY")
+		}
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		server := clientstest.NewServer()
+		t.Cleanup(server.Close)
+		clientstest.StartClients(t, server, "kabal-frontend")
+		clientstest.SetForTest(t, &staleThreshold, time.Hour)
+
+		status, body := checkReadiness(t)
+		if want := `{"ready":["kabal-frontend"],"not_ready":[],"stale":[]}`; status != http.StatusOK || body != want {
+			t.Errorf("got %d %s, want %d %s", status, body, http.StatusOK, want)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		server := clientstest.NewServer()
+		t.Cleanup(server.Close)
+		clientstest.StartClients(t, server, "kabal-frontend")
+		clientstest.SetForTest(t, &staleThreshold, time.Nanosecond)
+
+		status, body := checkReadiness(t)
+		if want := `{"ready":["kabal-frontend"],"not_ready":[],"stale":["kabal-frontend"]}`; status != http.StatusServiceUnavailable || body != want {
+			t.Errorf("got %d %s, want %d %s", status, body, http.StatusServiceUnavailable, want)
+		}
+	})
+
+	t.Run("overloaded", func(t *testing.T) {
+		feature.InitTracer()
+		server := clientstest.NewServer()
+		t.Cleanup(server.Close)
+		clientstest.StartClients(t, server, "kabal-frontend")
+		clientstest.SetForTest(t, &shedThreshold, 1)
+
+		if status, _ := checkReadiness(t); status != http.StatusOK {
+			t.Fatalf("status %d before any feature requests, want %d", status, http.StatusOK)
+		}
+
+		evaluator := clientstest.NewBlockingEvaluator(map[string]bool{"new-ui": true})
+		clients.RegisterEvaluator("kabal-frontend", evaluator)
+		t.Cleanup(func() { clients.UnregisterEvaluator("kabal-frontend") })
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r := httptest.NewRequest(http.MethodPost, feature.PathPrefix+"new-ui", strings.NewReader(`{"appName":"kabal-frontend"}`))
+			feature.Handler(httptest.NewRecorder(), r)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for feature.InFlight() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("feature request not in flight within 5s")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		status, body := checkReadiness(t)
+		close(evaluator.Release)
+		<-done
+
+		if status != http.StatusServiceUnavailable || body != "OVERLOADED" {
+			t.Errorf("got %d %q, want %d %q", status, body, http.StatusServiceUnavailable, "OVERLOADED")
+		}
+	})
+}
+
+func TestReadinessHandlerWithoutClients(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+	}{
+		{name: "never initialized", setup: func(t *testing.T) {}},
+		{name: "all closed", setup: func(t *testing.T) {
+			server := clientstest.NewServer()
+			t.Cleanup(server.Close)
+			clientstest.StartClients(t, server, "kabal-frontend")
+			clients.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+			if ready, _ := clients.ReadyApps(); len(ready) != 0 {
+				t.Fatalf("ready apps %v, want no clients", ready)
+			}
+
+			w := httptest.NewRecorder()
+			readinessHandler(w, httptest.NewRequest(http.MethodGet, "/isReady", nil))
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("status %d, want %d", w.Code, http.StatusServiceUnavailable)
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/plain" {
+				t.Errorf("Content-Type = %q, want text/plain", got)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != "NOT READY" {
+				t.Errorf("body %q, want NOT READY", got)
+			}
+		})
+	}
+}
+
+func TestRootEndpoint(t *testing.T) {
+	clientstest.SetForTest(t, &env.NaisAppName, "")
+	clientstest.SetForTest(t, &env.AppVersion, "2026.10.16")
+
+	server := httptest.NewServer(newHandler(nil))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var info serviceInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if info.Name != env.DefaultServiceName || info.Version != "2026.10.16" {
+		t.Errorf("name %q and version %q, want %q and %q", info.Name, info.Version, env.DefaultServiceName, "2026.10.16")
+	}
+	if got := info.Endpoints["feature"]; got != feature.PathPrefix+"{featureName}" {
+		t.Errorf("feature endpoint = %q, want %q", got, feature.PathPrefix+"{featureName}")
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	server := httptest.NewServer(newHandler(nil))
+	defer server.Close()
+
+	for _, path := range []string{"/unknown", "/isAlive/extra", "/admin/unknown"} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var response admin.NotFoundResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if response.Error != "not found" || !slices.Contains(response.KnownEndpoints, "/isReady") {
+				t.Errorf("response %+v, want not found listing /isReady", response)
+			}
+		})
+	}
+}
+
+// stuckProcessor is a span processor whose shutdown never finishes, like an exporter stuck on an unreachable collector.
+type stuckProcessor struct {
+	sdktrace.SpanProcessor
+	release chan struct{}
+}
+
+func (p stuckProcessor) Shutdown(ctx context.Context) error {
+	<-p.release
+	return nil
+}
+
+func TestShutdownTelemetryAbandonsStuckExporters(t *testing.T) {
+	clientstest.SetForTest(t, &telemetryShutdownTimeout, 50*time.Millisecond)
+
+	processor := stuckProcessor{SpanProcessor: sdktrace.NewSimpleSpanProcessor(tracetest.NewInMemoryExporter()), release: make(chan struct{})}
+	t.Cleanup(func() { close(processor.release) })
+	otelInstance := &telemetry.Telemetry{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))}
+
+	start := time.Now()
+	shutdownTelemetry(otelInstance)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdownTelemetry took %v, want it to give up after %v", elapsed, telemetryShutdownTimeout)
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+	shutdownStarted := make(chan struct{})
+	releaseShutdown := make(chan struct{})
+	exits := make(chan int, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		handleSignals(signals,
+			func() {
+				close(shutdownStarted)
+				// A stuck graceful shutdown
+				<-releaseShutdown
+			},
+			func(code int) { exits <- code },
+		)
+	}()
+
+	signals <- syscall.SIGTERM
+	select {
+	case <-shutdownStarted:
+	case <-time.After(time.Second):
+		t.Fatal("the first signal did not start a graceful shutdown")
+	}
+	select {
+	case code := <-exits:
+		t.Fatalf("exit(%d) after the first signal, want a graceful shutdown", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	signals <- syscall.SIGINT
+	select {
+	case code := <-exits:
+		if code != 1 {
+			t.Errorf("exit(%d) after the second signal, want exit(1)", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the second signal did not exit")
+	}
+
+	close(releaseShutdown)
+	<-done
+}
+
+func TestRoutesListsEveryRegisteredRoute(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	mux := http.NewServeMux()
+	routes := registerRoutes(mux)
+	list := routes.List()
+
+	// Every listed route is served by the mux under its own pattern
+	paths := strings.NewReplacer("{$}", "", "{name}", "new-ui")
+	for _, route := range list {
+		r := httptest.NewRequest(route.Methods[0], paths.Replace(route.Pattern), nil)
+		if _, pattern := mux.Handler(r); pattern != route.Pattern {
+			t.Errorf("%s is served by %q, want %q", r.URL.Path, pattern, route.Pattern)
+		}
+	}
+	if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/unknown", nil)); pattern != "/" {
+		t.Errorf("/unknown is served by %q, want the 404 catch-all", pattern)
+	}
+
+	// The startup log lists the same routes
+	var record struct {
+		Msg    string        `json:"msg"`
+		Routes []admin.Route `json:"routes"`
+	}
+	for line := range strings.Lines(buf.String()) {
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding log record: %v", err)
+		}
+		if strings.HasPrefix(record.Msg, "Registered") {
+			break
+		}
+	}
+	if record.Msg != fmt.Sprintf("Registered %d routes", len(list)) {
+		t.Fatalf("startup log message %q, want the registered routes", record.Msg)
+	}
+	if !slices.EqualFunc(record.Routes, list, routeEqual) {
+		t.Errorf("logged routes %v, want %v", record.Routes, list)
+	}
+
+	// And so does /admin/routes
+	clientstest.SetForTest(t, &env.AdminToken, "secret")
+	r := httptest.NewRequest(http.MethodGet, "/admin/routes", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	var served []admin.Route
+	if err := json.NewDecoder(w.Body).Decode(&served); err != nil {
+		t.Fatalf("decoding /admin/routes: %v", err)
+	}
+	if !slices.EqualFunc(served, list, routeEqual) {
+		t.Errorf("/admin/routes %v, want %v", served, list)
+	}
+}
+
+func routeEqual(a, b admin.Route) bool {
+	return a.Pattern == b.Pattern && slices.Equal(a.Methods, b.Methods)
+}
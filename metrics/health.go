@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientHealthCollector reports the health score of each Unleash client when scraped.
+type clientHealthCollector struct {
+	desc   *prometheus.Desc
+	mu     sync.RWMutex
+	scores map[string]func() float64
+}
+
+func newClientHealthCollector() *clientHealthCollector {
+	return &clientHealthCollector{
+		desc: prometheus.NewDesc(
+			"unleash_client_health",
+			"Health score of the Unleash client per app, between 0 (unhealthy) and 1 (healthy)",
+			[]string{"app_name"},
+			nil,
+		),
+		scores: make(map[string]func() float64),
+	}
+}
+
+func (c *clientHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *clientHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for appName, score := range c.scores {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, score(), appName)
+	}
+}
+
+// RegisterClientHealth reports the health score of the given app's Unleash client as unleash_client_health
+func RegisterClientHealth(appName string, score func() float64) {
+	clientHealth.mu.Lock()
+	defer clientHealth.mu.Unlock()
+
+	clientHealth.scores[appName] = score
+}
+
+// UnregisterClientHealth stops reporting the health score of the given app's Unleash client
+func UnregisterClientHealth(appName string) {
+	clientHealth.mu.Lock()
+	defer clientHealth.mu.Unlock()
+
+	delete(clientHealth.scores, appName)
+}
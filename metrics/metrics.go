@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
@@ -10,21 +11,97 @@ import (
 	"github.com/navikt/klage-unleash-proxy/env"
 )
 
-var (
-	defaultLabels = prometheus.Labels{
-		"app":       env.NaisAppName,
-		"version":   env.AppVersion,
-		"namespace": env.NaisNamespace,
-		"pod_name":  env.NaisPodName,
+// Label policy: metric labels must have bounded cardinality.
+// Per-user and per-pod request identifiers (navIdent, podName) must never be used as labels,
+// since every distinct value creates a new time series. TestMetricLabelsHaveBoundedCardinality checks this
+// for every registered metric, so add new metrics to it.
+// The default pod_name label is the proxy's own pod, which is constant per process.
+// Feature labels are only the names of features known to Unleash; the feature package labels all others "unknown".
+
+// appName returns the app label value, falling back to the default service name
+// when NAIS_APP_NAME is not set, e.g. in local runs.
+func appName() string {
+	if env.NaisAppName != "" {
+		return env.NaisAppName
 	}
+	return env.DefaultServiceName
+}
+
+var defaultLabels = prometheus.Labels{
+	"app":       appName(),
+	"version":   env.AppVersion,
+	"namespace": env.NaisNamespace,
+	"pod_name":  env.NaisPodName,
+}
+
+var (
+	// FeatureRequestsTotal counts the total number of feature check requests
+	FeatureRequestsTotal *prometheus.CounterVec
+
+	// FeatureRequestDuration tracks the duration of feature check requests
+	FeatureRequestDuration *prometheus.HistogramVec
+
+	// FeatureRequestBodySize tracks the size of feature check request bodies
+	FeatureRequestBodySize prometheus.Histogram
+
+	// FeatureRequestErrors counts errors during feature checks
+	FeatureRequestErrors *prometheus.CounterVec
+
+	// FeatureResponsesTotal counts feature endpoint responses by HTTP status code
+	FeatureResponsesTotal *prometheus.CounterVec
+
+	// FeatureFlipsTotal counts how often a feature's evaluated value changes for the same user
+	FeatureFlipsTotal *prometheus.CounterVec
+
+	// FeatureShadowMismatches counts shadow evaluations that disagreed with the primary evaluation
+	FeatureShadowMismatches *prometheus.CounterVec
 
-	// Create a wrapped registry with default labels
-	registry = prometheus.WrapRegistererWith(defaultLabels, prometheus.DefaultRegisterer)
+	// FeatureShadowDropped counts shadow evaluations skipped because too many were already running
+	FeatureShadowDropped prometheus.Counter
 
+	// FeatureOverridesTotal counts evaluations forced on or off for test users
+	FeatureOverridesTotal *prometheus.CounterVec
+
+	// FeatureEvaluationRetries counts evaluations retried because the feature was unknown
+	FeatureEvaluationRetries *prometheus.CounterVec
+
+	// UnregisteredAppRequests counts feature requests from apps that are not inbound applications
+	UnregisteredAppRequests *prometheus.CounterVec
+
+	// FeatureInversionsTotal counts feature results negated before being returned
+	FeatureInversionsTotal *prometheus.CounterVec
+
+	// FeatureCacheHits counts feature checks answered from the response cache
+	FeatureCacheHits *prometheus.CounterVec
+
+	// FeatureDecisionsDropped counts evaluation decisions that were not exported to the decision sink
+	FeatureDecisionsDropped *prometheus.CounterVec
+
+	// FeatureRequestsInFlight tracks the number of feature check requests currently being handled
+	FeatureRequestsInFlight prometheus.Gauge
+
+	// ConfigReloadsTotal counts reloads of the inbound applications by result
+	ConfigReloadsTotal *prometheus.CounterVec
+
+	// InboundApps tracks the current number of allowed inbound applications
+	InboundApps prometheus.Gauge
+
+	// clientHealth reports the health score of each Unleash client
+	clientHealth *clientHealthCollector
+)
+
+func init() {
+	Register(prometheus.DefaultRegisterer)
+}
+
+// Register creates all metrics and registers them with the given registerer, wrapped with the default labels.
+// Production uses prometheus.DefaultRegisterer. Tests can register with a fresh prometheus.NewRegistry()
+// to assert on metrics in isolation. Registering replaces the package's metrics.
+func Register(registerer prometheus.Registerer) {
 	// Use promauto.With() to register metrics with the wrapped registry
-	factory = promauto.With(registry)
+	wrapped := prometheus.WrapRegistererWith(defaultLabels, registerer)
+	factory := promauto.With(wrapped)
 
-	// FeatureRequestsTotal counts the total number of feature check requests
 	FeatureRequestsTotal = factory.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "feature_requests_total",
@@ -33,7 +110,6 @@ var (
 		[]string{"feature", "app_name", "enabled"},
 	)
 
-	// FeatureRequestDuration tracks the duration of feature check requests
 	FeatureRequestDuration = factory.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "feature_request_duration_seconds",
@@ -44,7 +120,15 @@ var (
 		[]string{"feature", "app_name"},
 	)
 
-	// FeatureRequestErrors counts errors during feature checks
+	FeatureRequestBodySize = factory.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "feature_request_body_bytes",
+			Help: "Size of feature check request bodies in bytes",
+			// JSON bodies are typically well under 1KB; larger buckets catch abnormal requests
+			Buckets: []float64{64, 128, 256, 512, 1024, 2048, 4096, 16384, 65536},
+		},
+	)
+
 	FeatureRequestErrors = factory.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "feature_request_errors_total",
@@ -52,7 +136,111 @@ var (
 		},
 		[]string{"error_type"},
 	)
-)
+
+	FeatureResponsesTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_responses_total",
+			Help: "Total number of feature endpoint responses, by HTTP status code",
+		},
+		[]string{"status"},
+	)
+
+	FeatureFlipsTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_flips_total",
+			Help: "Total number of times a feature's evaluated value changed for the same user",
+		},
+		[]string{"feature", "app_name"},
+	)
+
+	FeatureShadowMismatches = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_shadow_mismatch_total",
+			Help: "Total number of shadow evaluations that disagreed with the primary evaluation",
+		},
+		[]string{"feature"},
+	)
+
+	FeatureShadowDropped = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "feature_shadow_dropped_total",
+			Help: "Total number of shadow evaluations skipped because SHADOW_CONCURRENCY were already running",
+		},
+	)
+
+	FeatureOverridesTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_overrides_total",
+			Help: "Total number of feature evaluations forced on or off for test users",
+		},
+		[]string{"feature", "enabled"},
+	)
+
+	FeatureEvaluationRetries = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_evaluation_retries_total",
+			Help: "Total number of feature evaluations retried because the feature was unknown, by whether the retry found it",
+		},
+		[]string{"feature", "recovered"},
+	)
+
+	UnregisteredAppRequests = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "unregistered_app_requests_total",
+			Help: "Total number of feature requests from apps that are not inbound applications, in permissive mode",
+		},
+		[]string{"app_name"},
+	)
+
+	FeatureInversionsTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_inversions_total",
+			Help: "Total number of feature results negated before being returned, with ?invert=true or INVERTED_FEATURES",
+		},
+		[]string{"feature"},
+	)
+
+	FeatureCacheHits = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_cache_hits_total",
+			Help: "Total number of feature checks answered from the response cache, see FEATURE_CACHE_TTL",
+		},
+		[]string{"feature"},
+	)
+
+	FeatureDecisionsDropped = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feature_decisions_dropped_total",
+			Help: "Total number of evaluation decisions not exported to DECISION_SINK_URL, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	FeatureRequestsInFlight = factory.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "feature_requests_in_flight",
+			Help: "Number of feature check requests currently being handled",
+		},
+	)
+
+	ConfigReloadsTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Total number of reloads of the inbound applications, by result: success, error or not_initialized",
+		},
+		[]string{"result"},
+	)
+
+	InboundApps = factory.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "inbound_apps",
+			Help: "Current number of allowed inbound applications",
+		},
+	)
+
+	clientHealth = newClientHealthCollector()
+	wrapped.MustRegister(clientHealth)
+}
 
 // RecordFeatureRequest records metrics for a successful feature check
 func RecordFeatureRequest(feature, appName string, enabled bool, duration time.Duration) {
@@ -60,7 +248,97 @@ func RecordFeatureRequest(feature, appName string, enabled bool, duration time.D
 	FeatureRequestDuration.WithLabelValues(feature, appName).Observe(duration.Seconds())
 }
 
+// RecordRequestBodySize records the number of bytes read from a feature check request body
+func RecordRequestBodySize(bytes int64) {
+	FeatureRequestBodySize.Observe(float64(bytes))
+}
+
 // RecordFeatureError records an error during feature check
 func RecordFeatureError(errorType string) {
 	FeatureRequestErrors.WithLabelValues(errorType).Inc()
 }
+
+// RecordFeatureFlip records a change in a feature's evaluated value for the same user
+func RecordFeatureFlip(feature, appName string) {
+	FeatureFlipsTotal.WithLabelValues(feature, appName).Inc()
+}
+
+// RecordShadowMismatch records a shadow evaluation that disagreed with the primary evaluation
+func RecordShadowMismatch(feature string) {
+	FeatureShadowMismatches.WithLabelValues(feature).Inc()
+}
+
+// RecordShadowDropped records a shadow evaluation skipped because too many were already running
+func RecordShadowDropped() {
+	FeatureShadowDropped.Inc()
+}
+
+// RecordFeatureOverride records a feature evaluation forced on or off for a test user
+func RecordFeatureOverride(feature string, enabled bool) {
+	FeatureOverridesTotal.WithLabelValues(feature, strconv.FormatBool(enabled)).Inc()
+}
+
+// RecordEvaluationRetry records a retried feature evaluation and whether the retry found the feature
+func RecordEvaluationRetry(feature string, recovered bool) {
+	FeatureEvaluationRetries.WithLabelValues(feature, strconv.FormatBool(recovered)).Inc()
+}
+
+// RecordUnregisteredAppRequest records a feature request from an app that is not an inbound application
+func RecordUnregisteredAppRequest(appName string) {
+	UnregisteredAppRequests.WithLabelValues(appName).Inc()
+}
+
+// RecordFeatureInversion records a feature result negated before being returned
+func RecordFeatureInversion(feature string) {
+	FeatureInversionsTotal.WithLabelValues(feature).Inc()
+}
+
+// RecordFeatureCacheHit records a feature check answered from the response cache
+func RecordFeatureCacheHit(feature string) {
+	FeatureCacheHits.WithLabelValues(feature).Inc()
+}
+
+// RecordDecisionsDropped records evaluation decisions not exported to the decision sink
+func RecordDecisionsDropped(reason string, count int) {
+	FeatureDecisionsDropped.WithLabelValues(reason).Add(float64(count))
+}
+
+// RecordConfigReload records the result of a reload of the inbound applications
+func RecordConfigReload(result string) {
+	ConfigReloadsTotal.WithLabelValues(result).Inc()
+}
+
+// SetInboundApps records the current number of allowed inbound applications
+func SetInboundApps(count int) {
+	InboundApps.Set(float64(count))
+}
+
+// RecordFeatureResponse records the HTTP status code of a feature endpoint response
+func RecordFeatureResponse(status int) {
+	FeatureResponsesTotal.WithLabelValues(strconv.Itoa(status)).Inc()
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// StatusMiddleware returns an HTTP middleware that records the response status code of each request
+func StatusMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		next.ServeHTTP(wrapped, r)
+
+		RecordFeatureResponse(wrapped.statusCode)
+	})
+}
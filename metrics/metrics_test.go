@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestStatusMiddlewareCountsResponsesByStatus(t *testing.T) {
+	Register(prometheus.NewRegistry())
+
+	handler := StatusMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/features/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/features/broken":
+			http.Error(w, "broken", http.StatusInternalServerError)
+		default:
+			// An implicit 200 from writing the body without WriteHeader
+			_, _ = w.Write([]byte(`{"enabled":true}`))
+		}
+	}))
+
+	for _, path := range []string{"/features/a", "/features/b", "/features/missing", "/features/broken"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	for status, want := range map[string]float64{"200": 2, "404": 1, "500": 1} {
+		if got := testutil.ToFloat64(FeatureResponsesTotal.WithLabelValues(status)); got != want {
+			t.Errorf("feature_responses_total{status=%q} = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRegisterIncludesClientHealth(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	Register(registry)
+
+	RegisterClientHealth("kabal-frontend", func() float64 { return 0.5 })
+	defer UnregisterClientHealth("kabal-frontend")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if strings.HasSuffix(family.GetName(), "client_health") {
+			if got := family.GetMetric()[0].GetGauge().GetValue(); got != 0.5 {
+				t.Errorf("%s = %v, want 0.5", family.GetName(), got)
+			}
+			return
+		}
+	}
+	t.Error("client health is not registered")
+}
+
+// describingRegistry is a registry that counts the metric descriptors registered with it.
+type describingRegistry struct {
+	*prometheus.Registry
+	descs int
+}
+
+func (r *describingRegistry) Register(collector prometheus.Collector) error {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(descs)
+		close(descs)
+	}()
+	for range descs {
+		r.descs++
+	}
+	return r.Registry.Register(collector)
+}
+
+func (r *describingRegistry) MustRegister(collectors ...prometheus.Collector) {
+	for _, collector := range collectors {
+		if err := r.Register(collector); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestMetricLabelsHaveBoundedCardinality(t *testing.T) {
+	registry := &describingRegistry{Registry: prometheus.NewRegistry()}
+	Register(registry)
+
+	// Vectors are only gathered once they have a series, so every metric is recorded once
+	RecordFeatureRequest("new-ui", "kabal-frontend", true, time.Millisecond)
+	RecordRequestBodySize(64)
+	RecordFeatureError("invalid_json_body")
+	RecordFeatureResponse(http.StatusOK)
+	RecordFeatureFlip("new-ui", "kabal-frontend")
+	RecordShadowMismatch("new-ui")
+	RecordShadowDropped()
+	RecordFeatureOverride("new-ui", true)
+	RecordEvaluationRetry("new-ui", true)
+	RecordUnregisteredAppRequest("unregistered-app")
+	RecordFeatureInversion("new-ui")
+	RecordFeatureCacheHit("new-ui")
+	RecordDecisionsDropped("buffer_full", 1)
+	RecordConfigReload("success")
+	SetInboundApps(1)
+	FeatureRequestsInFlight.Set(1)
+	RegisterClientHealth("kabal-frontend", func() float64 { return 1 })
+	defer UnregisterClientHealth("kabal-frontend")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	if len(families) != registry.descs {
+		t.Fatalf("gathered %d metrics, want all %d registered: record new metrics in this test", len(families), registry.descs)
+	}
+
+	forbidden := []string{"nav_ident", "navIdent", "user_id", "userId", "pod_name", "podName"}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if !slices.Contains(forbidden, label.GetName()) {
+					continue
+				}
+				// The default pod_name label is the proxy's own pod, not the caller's
+				if label.GetName() == "pod_name" && label.GetValue() == defaultLabels["pod_name"] {
+					continue
+				}
+				t.Errorf("%s has the label %s=%q, which has unbounded cardinality", family.GetName(), label.GetName(), label.GetValue())
+			}
+		}
+	}
+}
+
+func TestAppNameFallsBackToDefaultServiceName(t *testing.T) {
+	previous := env.NaisAppName
+	t.Cleanup(func() { env.NaisAppName = previous })
+
+	env.NaisAppName = ""
+	if got := appName(); got != env.DefaultServiceName {
+		t.Errorf("appName() without NAIS_APP_NAME = %q, want %q", got, env.DefaultServiceName)
+	}
+
+	env.NaisAppName = "kabal-unleash-proxy-dev"
+	if got := appName(); got != "kabal-unleash-proxy-dev" {
+		t.Errorf("appName() = %q, want NAIS_APP_NAME", got)
+	}
+}
@@ -2,42 +2,178 @@ package nais
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/navikt/klage-unleash-proxy/env"
 )
 
 //go:embed nais.yaml
 var configYaml []byte
 
-// InboundApps is the list of allowed inbound applications from nais.yaml.
-// These correspond to the accessPolicy.inbound.rules in nais.yaml.
+// InboundApps is the list of allowed inbound applications from nais.yaml, as loaded at startup.
+// These correspond to the accessPolicy.inbound.rules in nais.yaml, and the other sources in NAIS_APP_SOURCES.
 var InboundApps []string
 
 func init() {
+	InboundApps = loadAtStartup()
+}
+
+// loadAtStartup returns the inbound applications from INBOUND_APPS if set, bypassing nais.yaml entirely.
+// Otherwise it reads them from the file at INBOUND_APPS_FILE if set,
+// so local runs and integration tests can use their own app list without rebuilding.
+// Otherwise it uses the embedded nais.yaml. It panics if the chosen document has no applications.
+// An unreadable INBOUND_APPS_FILE leaves InboundApps empty, since env.Validate fails startup on it,
+// logging the error once logging is set up.
+func loadAtStartup() []string {
+	if env.InboundApps != "" {
+		apps, err := fromEnv()
+		if err != nil {
+			panic(err.Error())
+		}
+		return apps
+	}
+
+	if env.InboundAppsFile != "" {
+		data, err := os.ReadFile(env.InboundAppsFile)
+		if err != nil {
+			return nil
+		}
+		apps, err := parse(data)
+		if err != nil {
+			panic(fmt.Sprintf("failed to parse %s: %v", env.InboundAppsFile, err))
+		}
+		return apps
+	}
+
+	apps, err := parse(configYaml)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse embedded nais.yaml: %v", err))
+	}
+
+	return apps
+}
+
+// Load reads the inbound applications again, from INBOUND_APPS or the file at INBOUND_APPS_FILE if set,
+// otherwise from the embedded nais.yaml. Unlike InboundApps, the result reflects changes made after startup.
+func Load() ([]string, error) {
+	if env.InboundApps != "" {
+		return fromEnv()
+	}
+
+	if env.InboundAppsFile == "" {
+		return parse(configYaml)
+	}
+
+	data, err := os.ReadFile(env.InboundAppsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read INBOUND_APPS_FILE: %w", err)
+	}
+
+	apps, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", env.InboundAppsFile, err)
+	}
+
+	return apps, nil
+}
+
+// fromEnv returns the inbound applications listed in INBOUND_APPS.
+func fromEnv() ([]string, error) {
+	apps := env.List(env.InboundApps)
+	if len(apps) == 0 {
+		return nil, errors.New("no applications found in INBOUND_APPS")
+	}
+	return apps, nil
+}
+
+// App sources in nais.yaml, selectable with NAIS_APP_SOURCES.
+const (
+	// SourceInbound is accessPolicy.inbound.rules.
+	SourceInbound = "inbound"
+	// SourceOutbound is accessPolicy.outbound.rules.
+	SourceOutbound = "outbound"
+	// SourceUnleash is the dedicated spec.unleash.allowedApps list.
+	SourceUnleash = "unleash"
+)
+
+// sources are the parts of nais.yaml apps are read from, from NAIS_APP_SOURCES. Defaults to inbound only.
+var sources = appSources()
+
+// appSources returns the sources in NAIS_APP_SOURCES, skipping invalid entries, which env.Validate reports
+// to fail startup.
+func appSources() []string {
+	var valid []string
+	for _, source := range env.NaisAppSources {
+		if source == SourceInbound || source == SourceOutbound || source == SourceUnleash {
+			valid = append(valid, source)
+		}
+	}
+
+	if len(valid) == 0 {
+		return []string{SourceInbound}
+	}
+	return valid
+}
+
+type rules []struct {
+	Application string `yaml:"application"`
+}
+
+// parse returns the allowed applications from a nais.yaml document, merged from each of the configured sources
+// in order and without duplicates.
+func parse(data []byte) ([]string, error) {
 	var config struct {
 		Spec struct {
 			AccessPolicy struct {
 				Inbound struct {
-					Rules []struct {
-						Application string `yaml:"application"`
-					} `yaml:"rules"`
+					Rules rules `yaml:"rules"`
 				} `yaml:"inbound"`
+				Outbound struct {
+					Rules rules `yaml:"rules"`
+				} `yaml:"outbound"`
 			} `yaml:"accessPolicy"`
+			Unleash struct {
+				AllowedApps []string `yaml:"allowedApps"`
+			} `yaml:"unleash"`
 		} `yaml:"spec"`
 	}
 
-	if err := yaml.Unmarshal(configYaml, &config); err != nil {
-		panic(fmt.Sprintf("failed to parse embedded nais.yaml: %v", err))
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
 	}
 
-	for _, rule := range config.Spec.AccessPolicy.Inbound.Rules {
-		if rule.Application != "" {
-			InboundApps = append(InboundApps, rule.Application)
+	var candidates []string
+	for _, source := range sources {
+		switch source {
+		case SourceInbound:
+			for _, rule := range config.Spec.AccessPolicy.Inbound.Rules {
+				candidates = append(candidates, rule.Application)
+			}
+		case SourceOutbound:
+			for _, rule := range config.Spec.AccessPolicy.Outbound.Rules {
+				candidates = append(candidates, rule.Application)
+			}
+		case SourceUnleash:
+			candidates = append(candidates, config.Spec.Unleash.AllowedApps...)
 		}
 	}
 
-	if len(InboundApps) == 0 {
-		panic("no inbound applications found in nais.yaml")
+	var apps []string
+	for _, app := range candidates {
+		if app != "" && !slices.Contains(apps, app) {
+			apps = append(apps, app)
+		}
 	}
+
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("no applications found in nais.yaml for sources %s", strings.Join(sources, ", "))
+	}
+
+	return apps, nil
 }
@@ -2,8 +2,10 @@ package telemetry
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/navikt/klage-unleash-proxy/trust"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
@@ -73,6 +75,12 @@ func NewMiddleware(enabled bool) (*Middleware, error) {
 	return m, nil
 }
 
+// shouldSkipTracing returns true for scrape and health check endpoints that should not create spans or request metrics.
+// Tracing every Prometheus scrape would otherwise produce telemetry about collecting telemetry.
+func shouldSkipTracing(path string) bool {
+	return path == "/metrics" || path == "/isAlive" || path == "/isReady"
+}
+
 // Handler wraps an http.Handler with OpenTelemetry instrumentation
 func (m *Middleware) Handler(next http.Handler) http.Handler {
 	if !m.enabled {
@@ -80,13 +88,24 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shouldSkipTracing(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		start := time.Now()
 
 		// Extract trace context from incoming request
 		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
 
-		// Start a new span
-		ctx, span := m.tracer.Start(ctx, r.Method+" "+r.URL.Path,
+		// Trusted callers can force this request to be traced, e.g. to capture a reported bad evaluation
+		forceSample := r.Header.Get("X-Force-Sample") == "true" && trust.FromTrustedProxy(r)
+		if forceSample {
+			ctx = WithForceSample(ctx)
+		}
+
+		// Start a new span, named after the route once it is known, see routeTemplate
+		ctx, span := m.tracer.Start(ctx, r.Method,
 			trace.WithSpanKind(trace.SpanKindServer),
 			trace.WithAttributes(
 				HTTPRequestMethodKey.String(r.Method),
@@ -95,6 +114,7 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 				ServerAddress(r.Host),
 				UserAgentOriginal(r.UserAgent()),
 				ClientAddress(r.RemoteAddr),
+				attribute.Bool("request.force_sample", forceSample),
 			),
 		)
 		defer span.End()
@@ -105,11 +125,18 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 			statusCode:     http.StatusOK,
 		}
 
-		// Call the next handler with the updated context
-		next.ServeHTTP(wrapped, r.WithContext(ctx))
+		// Call the next handler with the updated context.
+		// The mux sets the matched pattern on this request, which gives the route.
+		routed := r.WithContext(ctx)
+		next.ServeHTTP(wrapped, routed)
+		route := routeTemplate(routed.Pattern)
 
-		// Record the status code in the span
-		span.SetAttributes(HTTPResponseStatusCode(wrapped.statusCode))
+		// Record the route and status code in the span
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			HTTPRoute(route),
+			HTTPResponseStatusCode(wrapped.statusCode),
+		)
 
 		// Calculate duration
 		duration := time.Since(start).Seconds()
@@ -117,7 +144,7 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 		// Common attributes for metrics
 		attrs := []attribute.KeyValue{
 			HTTPRequestMethodKey.String(r.Method),
-			HTTPRoute(r.URL.Path),
+			HTTPRoute(route),
 			HTTPResponseStatusCode(wrapped.statusCode),
 		}
 
@@ -127,6 +154,28 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 	})
 }
 
+// routeTemplate returns the low-cardinality route for a matched mux pattern, for span names and the http.route attribute.
+// Subtree patterns like /features/ get a {name} placeholder for the rest of the path, e.g. /features/{name}.
+// The catch-all / pattern stays /, so unknown paths share a single route.
+func routeTemplate(pattern string) string {
+	// Drop the method and host of patterns like "GET /path"
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	// The {$} of exact patterns like "/{$}" only affects matching
+	pattern = strings.TrimSuffix(pattern, "{$}")
+
+	switch {
+	case pattern == "":
+		return "/"
+	case pattern != "/" && strings.HasSuffix(pattern, "/"):
+		return pattern + "{name}"
+	default:
+		return pattern
+	}
+}
+
 // scheme returns the HTTP scheme (http or https) for the request
 func scheme(r *http.Request) string {
 	if r.TLS != nil {
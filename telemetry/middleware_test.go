@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// withRecorder records the spans of a tracer provider with the given sampler for the duration of the test.
+// The middleware must be created afterwards, since it gets its tracer when created.
+func withRecorder(t *testing.T, sampler sdktrace.Sampler) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+// testMux serves the routes of the proxy that the span names are checked for.
+func testMux() *http.ServeMux {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux := http.NewServeMux()
+	mux.Handle("/{$}", ok)
+	mux.Handle("/isReady", ok)
+	mux.Handle("/metrics", ok)
+	mux.Handle("/features/", ok)
+	mux.Handle("GET /admin/stats", ok)
+	mux.Handle("/", http.NotFoundHandler())
+	return mux
+}
+
+func TestMiddlewareNamesSpansAfterRoutes(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		wantRoute string
+	}{
+		{method: http.MethodPost, path: "/features/new-ui", wantRoute: "/features/{name}"},
+		{method: "QUERY", path: "/features/another-feature", wantRoute: "/features/{name}"},
+		{method: http.MethodGet, path: "/", wantRoute: "/"},
+		{method: http.MethodGet, path: "/admin/stats", wantRoute: "/admin/stats"},
+		{method: http.MethodGet, path: "/unknown/path", wantRoute: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			recorder := withRecorder(t, sampler(Config{SampleRatio: 1}))
+			m, err := NewMiddleware(true)
+			if err != nil {
+				t.Fatalf("NewMiddleware: %v", err)
+			}
+
+			m.Handler(testMux()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans, want 1", len(spans))
+			}
+			if got, want := spans[0].Name(), tt.method+" "+tt.wantRoute; got != want {
+				t.Errorf("span name %q, want %q", got, want)
+			}
+			for _, attr := range spans[0].Attributes() {
+				if attr.Key == "http.route" && attr.Value.AsString() != tt.wantRoute {
+					t.Errorf("http.route %q, want %q", attr.Value.AsString(), tt.wantRoute)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareSkipsScrapesAndHealthChecks(t *testing.T) {
+	for _, path := range []string{"/metrics", "/isReady"} {
+		t.Run(path, func(t *testing.T) {
+			recorder := withRecorder(t, sampler(Config{SampleRatio: 1}))
+			m, err := NewMiddleware(true)
+			if err != nil {
+				t.Fatalf("NewMiddleware: %v", err)
+			}
+
+			m.Handler(testMux()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+			if spans := recorder.Ended(); len(spans) != 0 {
+				t.Errorf("%d spans, want none", len(spans))
+			}
+		})
+	}
+}
+
+func TestMiddlewareIgnoresForceSampleFromUntrustedClients(t *testing.T) {
+	recorder := withRecorder(t, sampler(Config{SampleRatio: 0}))
+	m, err := NewMiddleware(true)
+	if err != nil {
+		t.Fatalf("NewMiddleware: %v", err)
+	}
+
+	// No proxies are trusted in tests, so the header must not override the sampler
+	r := httptest.NewRequest(http.MethodPost, "/features/new-ui", nil)
+	r.Header.Set("X-Force-Sample", "true")
+	m.Handler(testMux()).ServeHTTP(httptest.NewRecorder(), r)
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("%d sampled spans, want none", len(spans))
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "", want: "/"},
+		{pattern: "/", want: "/"},
+		{pattern: "/{$}", want: "/"},
+		{pattern: "/isReady", want: "/isReady"},
+		{pattern: "/features/", want: "/features/{name}"},
+		{pattern: "GET /admin/stats", want: "/admin/stats"},
+		{pattern: "POST example.com/features/", want: "/features/{name}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := routeTemplate(tt.pattern); got != tt.want {
+				t.Errorf("routeTemplate(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,21 @@ package telemetry
 import (
 	"context"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/navikt/klage-unleash-proxy/env"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
@@ -24,6 +29,13 @@ type Config struct {
 	ServiceVersion string
 	Environment    string
 	OTLPEndpoint   string
+	// OTLPInsecure disables TLS for the OTLP exporters.
+	OTLPInsecure   bool
+	TracesEnabled  bool
+	MetricsEnabled bool
+	LogsEnabled    bool
+	// SampleRatio is the fraction of root traces sampled. Below 1, spans follow their parent's sampling decision.
+	SampleRatio float64
 }
 
 // ConfigFromEnv creates a Config from environment variables
@@ -56,13 +68,44 @@ func ConfigFromEnv() Config {
 		ServiceVersion: serviceVersion,
 		Environment:    environment,
 		OTLPEndpoint:   otlpEndpoint,
+		OTLPInsecure:   otlpInsecure(otlpEndpoint),
+		TracesEnabled:  env.OtelTracesEnabled,
+		MetricsEnabled: env.OtelMetricsEnabled,
+		LogsEnabled:    env.OtelLogsEnabled,
+		SampleRatio:    env.Ratio("OTEL_TRACES_SAMPLER_ARG", env.OtelTracesSamplerArg, 1),
 	}
 }
 
-// Telemetry holds the OpenTelemetry providers
+// otlpInsecure reports whether the OTLP exporters should connect without TLS.
+// OTEL_EXPORTER_OTLP_INSECURE decides if set. Otherwise TLS is only used for https:// endpoints,
+// keeping plaintext for the in-mesh collector.
+func otlpInsecure(endpoint string) bool {
+	if env.OtelExporterOTLPInsecure != "" {
+		insecure, err := strconv.ParseBool(env.OtelExporterOTLPInsecure)
+		if err == nil {
+			return insecure
+		}
+		slog.Warn("Invalid boolean in OTEL_EXPORTER_OTLP_INSECURE, using the endpoint scheme",
+			slog.String("value", env.OtelExporterOTLPInsecure),
+		)
+	}
+	return !strings.HasPrefix(endpoint, "https://")
+}
+
+// sampler returns the trace sampler for the configured ratio, sampling everything at 1.
+// Requests forced with X-Force-Sample are sampled regardless.
+func sampler(cfg Config) trace.Sampler {
+	if cfg.SampleRatio >= 1 {
+		return forceSampler{base: trace.AlwaysSample()}
+	}
+	return forceSampler{base: trace.ParentBased(trace.TraceIDRatioBased(cfg.SampleRatio))}
+}
+
+// Telemetry holds the OpenTelemetry providers. A provider is nil if its signal is disabled.
 type Telemetry struct {
 	TracerProvider *trace.TracerProvider
 	MeterProvider  *metric.MeterProvider
+	LoggerProvider *sdklog.LoggerProvider
 }
 
 // Shutdown gracefully shuts down the telemetry providers
@@ -80,10 +123,17 @@ func (t *Telemetry) Shutdown(ctx context.Context) error {
 			slog.Error("Failed to shutdown meter provider", slog.String("error", e.Error()))
 		}
 	}
+	if t.LoggerProvider != nil {
+		if e := t.LoggerProvider.Shutdown(ctx); e != nil {
+			err = e
+			slog.Error("Failed to shutdown logger provider", slog.String("error", e.Error()))
+		}
+	}
 	return err
 }
 
-// Initialize sets up OpenTelemetry with tracing and metrics
+// Initialize sets up OpenTelemetry with each of tracing, metrics and log export that is enabled.
+// On error, anything already set up is shut down and no global providers are registered.
 func Initialize(ctx context.Context, cfg Config) (*Telemetry, error) {
 	logger := slog.Default()
 
@@ -93,11 +143,23 @@ func Initialize(ctx context.Context, cfg Config) (*Telemetry, error) {
 		return nil, nil
 	}
 
+	if !cfg.TracesEnabled && !cfg.MetricsEnabled && !cfg.LogsEnabled {
+		logger.Info("OpenTelemetry disabled: traces, metrics and logs are all disabled")
+		return nil, nil
+	}
+
+	traceSampler := sampler(cfg)
+
 	logger.Info("Initializing OpenTelemetry",
 		slog.String("service_name", cfg.ServiceName),
 		slog.String("service_version", cfg.ServiceVersion),
 		slog.String("environment", cfg.Environment),
 		slog.String("otlp_endpoint", cfg.OTLPEndpoint),
+		slog.Bool("otlp_insecure", cfg.OTLPInsecure),
+		slog.Bool("traces_enabled", cfg.TracesEnabled),
+		slog.Bool("metrics_enabled", cfg.MetricsEnabled),
+		slog.Bool("logs_enabled", cfg.LogsEnabled),
+		slog.String("sampler", traceSampler.Description()),
 	)
 
 	// Create resource with service information
@@ -117,35 +179,99 @@ func Initialize(ctx context.Context, cfg Config) (*Telemetry, error) {
 
 	telemetry := &Telemetry{}
 
-	// Set up trace exporter with retry logic
-	traceExporter, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithTimeout(10*time.Second),
-		otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
-			Enabled:         true,
-			InitialInterval: 1 * time.Second,
-			MaxInterval:     5 * time.Second,
-			MaxElapsedTime:  30 * time.Second,
-		}),
-		otlptracegrpc.WithDialOption(grpc.WithDefaultCallOptions(
-			grpc.MaxCallSendMsgSize(4*1024*1024), // 4MB max message size
-		)),
-	)
-	if err != nil {
-		return nil, err
+	if cfg.TracesEnabled {
+		// Set up trace exporter with retry logic
+		traceExporter, err := otlptracegrpc.New(ctx,
+			traceSecurity(cfg),
+			otlptracegrpc.WithTimeout(10*time.Second),
+			otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+				Enabled:         true,
+				InitialInterval: 1 * time.Second,
+				MaxInterval:     5 * time.Second,
+				MaxElapsedTime:  30 * time.Second,
+			}),
+			otlptracegrpc.WithDialOption(grpc.WithDefaultCallOptions(
+				grpc.MaxCallSendMsgSize(4*1024*1024), // 4MB max message size
+			)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create tracer provider
+		telemetry.TracerProvider = trace.NewTracerProvider(
+			trace.WithBatcher(traceExporter,
+				trace.WithBatchTimeout(5*time.Second),
+			),
+			trace.WithResource(res),
+			trace.WithSampler(traceSampler),
+		)
 	}
 
-	// Create tracer provider
-	telemetry.TracerProvider = trace.NewTracerProvider(
-		trace.WithBatcher(traceExporter,
-			trace.WithBatchTimeout(5*time.Second),
-		),
-		trace.WithResource(res),
-		trace.WithSampler(trace.AlwaysSample()),
-	)
+	if cfg.MetricsEnabled {
+		// Set up metrics exporter with retry logic
+		metricExporter, err := otlpmetricgrpc.New(ctx,
+			metricSecurity(cfg),
+			otlpmetricgrpc.WithTimeout(10*time.Second),
+			otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+				Enabled:         true,
+				InitialInterval: 1 * time.Second,
+				MaxInterval:     5 * time.Second,
+				MaxElapsedTime:  30 * time.Second,
+			}),
+			otlpmetricgrpc.WithDialOption(grpc.WithDefaultCallOptions(
+				grpc.MaxCallSendMsgSize(4*1024*1024), // 4MB max message size
+			)),
+		)
+		if err != nil {
+			telemetry.Shutdown(ctx)
+			return nil, err
+		}
 
-	// Set global tracer provider
-	otel.SetTracerProvider(telemetry.TracerProvider)
+		// Create meter provider
+		telemetry.MeterProvider = metric.NewMeterProvider(
+			metric.WithResource(res),
+			metric.WithReader(metric.NewPeriodicReader(metricExporter,
+				metric.WithInterval(30*time.Second),
+			)),
+		)
+	}
+
+	if cfg.LogsEnabled {
+		// Set up log exporter with retry logic
+		logExporter, err := otlploggrpc.New(ctx,
+			logSecurity(cfg),
+			otlploggrpc.WithTimeout(10*time.Second),
+			otlploggrpc.WithRetry(otlploggrpc.RetryConfig{
+				Enabled:         true,
+				InitialInterval: 1 * time.Second,
+				MaxInterval:     5 * time.Second,
+				MaxElapsedTime:  30 * time.Second,
+			}),
+			otlploggrpc.WithDialOption(grpc.WithDefaultCallOptions(
+				grpc.MaxCallSendMsgSize(4*1024*1024), // 4MB max message size
+			)),
+		)
+		if err != nil {
+			telemetry.Shutdown(ctx)
+			return nil, err
+		}
+
+		// Create logger provider
+		telemetry.LoggerProvider = sdklog.NewLoggerProvider(
+			sdklog.WithResource(res),
+			sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)),
+		)
+	}
+
+	// Register the global providers only once all exporters are set up, so a failure leaves no partial state.
+	// A disabled signal keeps the global no-op provider.
+	if telemetry.TracerProvider != nil {
+		otel.SetTracerProvider(telemetry.TracerProvider)
+	}
+	if telemetry.MeterProvider != nil {
+		otel.SetMeterProvider(telemetry.MeterProvider)
+	}
 
 	// Set up propagator for trace context propagation
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
@@ -153,36 +279,31 @@ func Initialize(ctx context.Context, cfg Config) (*Telemetry, error) {
 		propagation.Baggage{},
 	))
 
-	// Set up metrics exporter with retry logic
-	metricExporter, err := otlpmetricgrpc.New(ctx,
-		otlpmetricgrpc.WithInsecure(),
-		otlpmetricgrpc.WithTimeout(10*time.Second),
-		otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
-			Enabled:         true,
-			InitialInterval: 1 * time.Second,
-			MaxInterval:     5 * time.Second,
-			MaxElapsedTime:  30 * time.Second,
-		}),
-		otlpmetricgrpc.WithDialOption(grpc.WithDefaultCallOptions(
-			grpc.MaxCallSendMsgSize(4*1024*1024), // 4MB max message size
-		)),
-	)
-	if err != nil {
-		return telemetry, err
-	}
+	logger.Info("OpenTelemetry initialized successfully")
 
-	// Create meter provider
-	telemetry.MeterProvider = metric.NewMeterProvider(
-		metric.WithResource(res),
-		metric.WithReader(metric.NewPeriodicReader(metricExporter,
-			metric.WithInterval(30*time.Second),
-		)),
-	)
+	return telemetry, nil
+}
 
-	// Set global meter provider
-	otel.SetMeterProvider(telemetry.MeterProvider)
+// traceSecurity returns the option to connect the trace exporter with or without TLS.
+func traceSecurity(cfg Config) otlptracegrpc.Option {
+	if cfg.OTLPInsecure {
+		return otlptracegrpc.WithInsecure()
+	}
+	return otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+}
 
-	logger.Info("OpenTelemetry initialized successfully")
+// metricSecurity returns the option to connect the metric exporter with or without TLS.
+func metricSecurity(cfg Config) otlpmetricgrpc.Option {
+	if cfg.OTLPInsecure {
+		return otlpmetricgrpc.WithInsecure()
+	}
+	return otlpmetricgrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+}
 
-	return telemetry, nil
+// logSecurity returns the option to connect the log exporter with or without TLS.
+func logSecurity(cfg Config) otlploggrpc.Option {
+	if cfg.OTLPInsecure {
+		return otlploggrpc.WithInsecure()
+	}
+	return otlploggrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
 }
@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+func TestOTLPInsecure(t *testing.T) {
+	tests := []struct {
+		name     string
+		insecure string
+		endpoint string
+		want     bool
+	}{
+		{name: "in-mesh collector", endpoint: "http://otel-collector:4317", want: true},
+		{name: "host and port", endpoint: "otel-collector:4317", want: true},
+		{name: "https endpoint", endpoint: "https://otel.example.com:4317", want: false},
+		{name: "forced insecure", insecure: "true", endpoint: "https://otel.example.com:4317", want: true},
+		{name: "forced TLS", insecure: "false", endpoint: "http://otel-collector:4317", want: false},
+		{name: "invalid falls back to scheme", insecure: "maybe", endpoint: "https://otel.example.com:4317", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := env.OtelExporterOTLPInsecure
+			env.OtelExporterOTLPInsecure = tt.insecure
+			t.Cleanup(func() { env.OtelExporterOTLPInsecure = previous })
+
+			if got := otlpInsecure(tt.endpoint); got != tt.want {
+				t.Errorf("otlpInsecure(%q) with OTEL_EXPORTER_OTLP_INSECURE=%q = %v, want %v", tt.endpoint, tt.insecure, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type forceSampleKey struct{}
+
+// WithForceSample returns a context in which all spans are sampled, regardless of the configured sampler.
+func WithForceSample(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceSampleKey{}, true)
+}
+
+// ForceSampled reports whether sampling is forced for the context, see WithForceSample.
+func ForceSampled(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceSampleKey{}).(bool)
+	return forced
+}
+
+// forceSampler samples spans started in a force-sampled context, and defers to base for all others.
+type forceSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s forceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if ForceSampled(p.ParentContext) {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s forceSampler) Description() string {
+	return "ForceSampler{" + s.base.Description() + "}"
+}
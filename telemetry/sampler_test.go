@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSampler(t *testing.T) {
+	// A trace ID above every ratio below 1, so ratio sampling drops it
+	traceID := trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	tests := []struct {
+		name   string
+		ratio  float64
+		forced bool
+		want   sdktrace.SamplingDecision
+	}{
+		{name: "always", ratio: 1, want: sdktrace.RecordAndSample},
+		{name: "never", ratio: 0, want: sdktrace.Drop},
+		{name: "ratio", ratio: 0.5, want: sdktrace.Drop},
+		{name: "never but forced", ratio: 0, forced: true, want: sdktrace.RecordAndSample},
+		{name: "ratio but forced", ratio: 0.5, forced: true, want: sdktrace.RecordAndSample},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.forced {
+				ctx = WithForceSample(ctx)
+			}
+
+			result := sampler(Config{SampleRatio: tt.ratio}).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: ctx,
+				TraceID:       traceID,
+				Name:          "GET /",
+			})
+			if result.Decision != tt.want {
+				t.Errorf("decision %v, want %v", result.Decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerFollowsSampledParent(t *testing.T) {
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0xff},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	result := sampler(Config{SampleRatio: 0}).ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithRemoteSpanContext(context.Background(), parent),
+		TraceID:       parent.TraceID(),
+		Name:          "GET /",
+	})
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Errorf("decision %v, want the sampled parent's decision", result.Decision)
+	}
+}
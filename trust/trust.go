@@ -0,0 +1,66 @@
+package trust
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/navikt/klage-unleash-proxy/env"
+)
+
+// proxies are the networks of trusted proxies, parsed from TRUSTED_PROXY_CIDRS.
+// TRUSTED_PROXY_CIDRS is checked by env.Validate at startup. If it is invalid, no proxy is trusted.
+var proxies, _ = parsePrefixes(env.TrustedProxyCIDRs)
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TRUSTED_PROXY_CIDRS entry %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// FromTrustedProxy reports whether the request's connection comes from a trusted proxy.
+// Headers that change how a request is handled, like X-Debug, are only honored from trusted proxies.
+// Always false when TRUSTED_PROXY_CIDRS is not set.
+func FromTrustedProxy(r *http.Request) bool {
+	if len(proxies) == 0 {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Trust makes FromTrustedProxy trust the given networks instead of TRUSTED_PROXY_CIDRS, returning a func that
+// restores the previous ones. It is meant for tests of handlers that honor headers from trusted proxies.
+func Trust(cidrs ...string) (restore func()) {
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	previous := proxies
+	proxies = prefixes
+	return func() { proxies = previous }
+}
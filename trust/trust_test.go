@@ -0,0 +1,48 @@
+package trust
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromTrustedProxy(t *testing.T) {
+	tests := []struct {
+		name       string
+		cidrs      []string
+		remoteAddr string
+		want       bool
+	}{
+		{name: "no trusted proxies", remoteAddr: "10.0.0.1:1234", want: false},
+		{name: "inside", cidrs: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", want: true},
+		{name: "outside", cidrs: []string{"10.0.0.0/8"}, remoteAddr: "192.168.0.1:1234", want: false},
+		{name: "IPv6 inside", cidrs: []string{"fd00::/8"}, remoteAddr: "[fd00::1]:1234", want: true},
+		{name: "IPv4-mapped IPv6", cidrs: []string{"10.0.0.0/8"}, remoteAddr: "[::ffff:10.0.0.1]:1234", want: true},
+		{name: "unmasked CIDR", cidrs: []string{"10.1.2.3/8"}, remoteAddr: "10.200.0.1:1234", want: true},
+		{name: "without port", cidrs: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1", want: true},
+		{name: "not an address", cidrs: []string{"10.0.0.0/8"}, remoteAddr: "pipe", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefixes, err := parsePrefixes(tt.cidrs)
+			if err != nil {
+				t.Fatalf("parsePrefixes: %v", err)
+			}
+			previous := proxies
+			proxies = prefixes
+			t.Cleanup(func() { proxies = previous })
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if got := FromTrustedProxy(r); got != tt.want {
+				t.Errorf("FromTrustedProxy(%s) = %v, want %v", tt.remoteAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrefixesRejectsMalformedCIDRs(t *testing.T) {
+	if _, err := parsePrefixes([]string{"10.0.0.0/8", "not-a-cidr"}); err == nil {
+		t.Error("parsePrefixes accepted a malformed CIDR")
+	}
+}